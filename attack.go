@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
 	"os"
@@ -18,25 +21,59 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tsenart/vegeta/v12/internal/resolver"
 	vegeta "github.com/tsenart/vegeta/v12/lib"
+	"github.com/tsenart/vegeta/v12/lib/influx"
+	"github.com/tsenart/vegeta/v12/lib/otlp"
 	prom "github.com/tsenart/vegeta/v12/lib/prom"
+	"github.com/tsenart/vegeta/v12/lib/statsd"
 )
 
 func attackCmd() command {
 	fs := flag.NewFlagSet("vegeta attack", flag.ExitOnError)
-	opts := &attackOpts{
-		headers:      headers{http.Header{}},
-		proxyHeaders: headers{http.Header{}},
-		laddr:        localAddr{&vegeta.DefaultLocalAddr},
-		rate:         vegeta.Rate{Freq: 50, Per: time.Second},
-		maxBody:      vegeta.DefaultMaxBody,
-		promAddr:     "0.0.0.0:8880",
+	opts := newAttackOpts()
+	fs.StringVar(&opts.targetsf, "targets", "stdin", "Targets file")
+	fs.BoolVar(&opts.lazy, "lazy", false, "Read targets lazily")
+	fs.DurationVar(&opts.duration, "duration", 0, "Duration of the test [0 = forever]")
+	fs.Var(&rateFlag{&opts.rate}, "rate", "Number of requests per time unit [0 = infinity]")
+	fs.BoolVar(&opts.harTiming, "har-timing", false, "Send requests with the timing recorded in the HAR targets file instead of -rate and -duration")
+	fs.BoolVar(&opts.logTiming, "access-log-timing", false, "Send requests with the timing logged in the access log targets file instead of -rate and -duration")
+	attackerFlags(fs, opts)
+
+	return command{fs, func(args []string) error {
+		fs.Parse(args)
+		return attack(opts)
+	}}
+}
+
+// newAttackOpts returns attackOpts with their default values set.
+func newAttackOpts() *attackOpts {
+	return &attackOpts{
+		headers:       headers{http.Header{}},
+		proxyHeaders:  headers{http.Header{}},
+		otlpHeaders:   headers{http.Header{}},
+		laddrs:        localAddrs{vegeta.DefaultLocalAddr},
+		laddrRotation: laddrRoundRobin,
+		rate:          vegeta.Rate{Freq: 50, Per: time.Second},
+		maxBody:       vegeta.DefaultMaxBody,
+		tickQueue:     vegeta.BlockTicks,
+		promAddr:      "0.0.0.0:8880",
 	}
+}
+
+// attackerFlags defines the flags shared by the commands that run attacks,
+// which configure the Attacker, the targets format and the results output.
+func attackerFlags(fs *flag.FlagSet, opts *attackOpts) {
 	fs.StringVar(&opts.name, "name", "", "Attack name")
-	fs.StringVar(&opts.targetsf, "targets", "stdin", "Targets file")
 	fs.StringVar(&opts.format, "format", vegeta.HTTPTargetFormat,
 		fmt.Sprintf("Targets format [%s]", strings.Join(vegeta.TargetFormats, ", ")))
 	fs.StringVar(&opts.outputf, "output", "stdout", "Output file")
 	fs.StringVar(&opts.bodyf, "body", "", "Requests body file")
+	fs.Var((*csl)(&opts.har.Hosts), "har-hosts", "Only read HAR targets with one of these hosts (comma separated list)")
+	fs.Var((*csl)(&opts.har.MimeTypes), "har-mime-types", "Only read HAR targets whose response has one of these mime types, e.g. application/json,text/* (comma separated list)")
+	fs.BoolVar(&opts.har.DropCookies, "har-drop-cookies", false, "Drop the Cookie header of HAR targets")
+	fs.StringVar(&opts.accessLog.BaseURL, "access-log-base-url", "", "Base URL to which the requests of access log targets are sent, e.g. http://localhost:8080")
+	fs.Var(&accessLogFormatFlag{&opts.accessLog.Format}, "access-log-format", "Format of access log targets [combined, common, <regex>]")
+	fs.Var((*csl)(&opts.accessLog.SkipMethods), "access-log-skip-methods", "Skip access log targets with one of these methods (comma separated list)")
+	fs.Var((*csl)(&opts.accessLog.SkipPaths), "access-log-skip-paths", "Skip access log targets with one of these paths, or prefixes when ending in * (comma separated list)")
 	fs.BoolVar(&opts.chunked, "chunked", false, "Send body with chunked transfer encoding")
 	fs.StringVar(&opts.certf, "cert", "", "TLS client PEM encoded certificate file")
 	fs.StringVar(&opts.keyf, "key", "", "TLS client PEM encoded private key file")
@@ -44,31 +81,44 @@ func attackCmd() command {
 	fs.BoolVar(&opts.http2, "http2", true, "Send HTTP/2 requests when supported by the server")
 	fs.BoolVar(&opts.h2c, "h2c", false, "Send HTTP/2 requests without TLS encryption")
 	fs.BoolVar(&opts.insecure, "insecure", false, "Ignore invalid server TLS certificates")
-	fs.BoolVar(&opts.lazy, "lazy", false, "Read targets lazily")
-	fs.DurationVar(&opts.duration, "duration", 0, "Duration of the test [0 = forever]")
 	fs.DurationVar(&opts.timeout, "timeout", vegeta.DefaultTimeout, "Requests timeout")
 	fs.Uint64Var(&opts.workers, "workers", vegeta.DefaultWorkers, "Initial number of workers")
 	fs.Uint64Var(&opts.maxWorkers, "max-workers", vegeta.DefaultMaxWorkers, "Maximum number of workers")
+	fs.Var(&tickQueueFlag{&opts.tickQueue}, "max-workers-policy", "What to do with requests due while all -max-workers are busy [block, drop, queue:N]")
 	fs.IntVar(&opts.connections, "connections", vegeta.DefaultConnections, "Max open idle connections per target host")
 	fs.IntVar(&opts.maxConnections, "max-connections", vegeta.DefaultMaxConnections, "Max connections per target host")
 	fs.IntVar(&opts.redirects, "redirects", vegeta.DefaultRedirects, "Number of redirects to follow. -1 will not follow but marks as success")
 	fs.Var(&maxBodyFlag{&opts.maxBody}, "max-body", "Maximum number of bytes to capture from response bodies. [-1 = no limit]")
-	fs.Var(&rateFlag{&opts.rate}, "rate", "Number of requests per time unit [0 = infinity]")
+	fs.Var(&stopOnFlag{&opts.stops}, "stop-on", "Stop the attack early when a condition is met [error-ratio:R[/N], consecutive-failures:N, pNN:D[/N]]. Can be repeated multiple times")
+	fs.Var(&bodyCaptureFlag{&opts.capture}, "capture-body", "Which response bodies to capture up to -max-body [all, or a comma separated list of errors, sample:F, first:N]")
 	fs.Var(&opts.headers, "header", "Request header")
 	fs.Var(&opts.proxyHeaders, "proxy-header", "Proxy CONNECT header")
-	fs.Var(&opts.laddr, "laddr", "Local IP address")
+	fs.Var(&opts.laddrs, "laddr", "Local IP addresses or CIDR ranges to send requests from (comma separated list)")
+	fs.StringVar(&opts.laddrRotation, "laddr-rotation", laddrRoundRobin, fmt.Sprintf("Rotation of connections across many local addresses [%s, %s]", laddrRoundRobin, laddrRandom))
 	fs.BoolVar(&opts.keepalive, "keepalive", true, "Use persistent connections")
 	fs.StringVar(&opts.unixSocket, "unix-socket", "", "Connect over a unix socket. This overrides the host address in target URLs")
 	fs.StringVar(&opts.promAddr, "prometheus-addr", "", "Prometheus exporter listen address [empty = disabled]. Example: 0.0.0.0:8880")
+	fs.StringVar(&opts.promPushgateway, "prometheus-pushgateway", "", "Prometheus Pushgateway URL to push metrics to [empty = disabled]. Example: http://localhost:9091")
+	fs.StringVar(&opts.promRemoteWrite, "prometheus-remote-write", "", "Prometheus remote-write URL to push metrics to [empty = disabled]. Example: http://localhost:9090/api/v1/write")
+	fs.DurationVar(&opts.promPushEvery, "prometheus-push-interval", 10*time.Second, "Interval of Prometheus metrics pushes, besides the final one")
+	fs.StringVar(&opts.promJob, "prometheus-job", "vegeta", "Job label of pushed Prometheus metrics")
+	fs.StringVar(&opts.promInstance, "prometheus-instance", "", "Instance label of pushed Prometheus metrics [empty = hostname]")
+	fs.IntVar(&opts.urls.Max, "prometheus-max-urls", prom.DefaultMaxURLs, "Maximum number of distinct url labels of Prometheus metrics and tags of -influx points, beyond which they're \"(other)\" [0 = no limit]")
+	fs.Var(&urlRulesFlag{&opts.urls.Rules}, "url-rule", "Normalize URLs of Prometheus metrics and -influx points matching a regexp with a template, e.g. /users/\\d+=>/users/:id. Can be repeated multiple times")
+	fs.BoolVar(&opts.urls.DetectIDs, "url-ids", false, "Normalize IDs in URLs of Prometheus metrics and -influx points, such as numbers and UUIDs, to :id")
+	fs.StringVar(&opts.influx, "influx", "", "File or InfluxDB write endpoint URL to write results to in the line protocol [empty = disabled]. Example: http://localhost:8086/api/v2/write?org=o&bucket=b")
+	fs.StringVar(&opts.influxToken, "influx-token", "", "InfluxDB API token")
+	fs.StringVar(&opts.statsd, "statsd", "", "StatsD server UDP address to send result metrics to [empty = disabled]. Example: localhost:8125")
+	fs.StringVar(&opts.dogstatsd, "dogstatsd", "", "DogStatsD server UDP address to send tagged result metrics to [empty = disabled]. Example: localhost:8125")
+	fs.DurationVar(&opts.sinkEvery, "sink-interval", 0, "Interval of the aggregate metrics sent to -influx, -statsd and -dogstatsd [0 = per result]")
+	fs.BoolVar(&opts.trace, "trace", false, "Send a W3C Trace Context traceparent header with a new trace ID in every request")
+	fs.StringVar(&opts.otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP traces endpoint URL to export client spans to, implying -trace [empty = disabled]. Example: http://localhost:4318/v1/traces")
+	fs.StringVar(&opts.otlpFile, "otlp-file", "", "File to export client spans to as OTLP/JSON lines, implying -trace [empty = disabled]")
+	fs.Var(&opts.otlpHeaders, "otlp-header", "OTLP/HTTP request header")
 	fs.Var(&dnsTTLFlag{&opts.dnsTTL}, "dns-ttl", "Cache DNS lookups for the given duration [-1 = disabled, 0 = forever]")
 	fs.BoolVar(&opts.sessionTickets, "session-tickets", false, "Enable TLS session resumption using session tickets")
 	fs.Var(&connectToFlag{&opts.connectTo}, "connect-to", "A mapping of (ip|host):port to use instead of a target URL's (ip|host):port. Can be repeated multiple times.\nIdentical src:port with different dst:port will round-robin over the different dst:port pairs.\nExample: google.com:80:localhost:6060")
 	systemSpecificFlags(fs, opts)
-
-	return command{fs, func(args []string) error {
-		fs.Parse(args)
-		return attack(opts)
-	}}
 }
 
 var (
@@ -76,58 +126,92 @@ var (
 	errBadCert  = errors.New("bad certificate")
 )
 
+// Rotation strategies of connections across many local addresses.
+const (
+	laddrRoundRobin = "round-robin"
+	laddrRandom     = "random"
+)
+
 // attackOpts aggregates the attack function command options
 type attackOpts struct {
-	name           string
-	targetsf       string
-	format         string
-	outputf        string
-	bodyf          string
-	certf          string
-	keyf           string
-	rootCerts      csl
-	http2          bool
-	h2c            bool
-	insecure       bool
-	lazy           bool
-	chunked        bool
-	duration       time.Duration
-	timeout        time.Duration
-	rate           vegeta.Rate
-	workers        uint64
-	maxWorkers     uint64
-	connections    int
-	maxConnections int
-	redirects      int
-	maxBody        int64
-	headers        headers
-	proxyHeaders   headers
-	laddr          localAddr
-	keepalive      bool
-	resolvers      csl
-	unixSocket     string
-	promAddr       string
-	dnsTTL         time.Duration
-	sessionTickets bool
-	connectTo      map[string][]string
+	name            string
+	targetsf        string
+	format          string
+	outputf         string
+	bodyf           string
+	certf           string
+	keyf            string
+	rootCerts       csl
+	http2           bool
+	h2c             bool
+	insecure        bool
+	lazy            bool
+	har             vegeta.HAROptions
+	harTiming       bool
+	accessLog       vegeta.AccessLogOptions
+	logTiming       bool
+	chunked         bool
+	duration        time.Duration
+	timeout         time.Duration
+	rate            vegeta.Rate
+	workers         uint64
+	maxWorkers      uint64
+	tickQueue       int
+	connections     int
+	maxConnections  int
+	redirects       int
+	maxBody         int64
+	capture         vegeta.BodyCapture
+	stops           []vegeta.StopCondition
+	headers         headers
+	proxyHeaders    headers
+	laddrs          localAddrs
+	laddrRotation   string
+	keepalive       bool
+	resolvers       csl
+	unixSocket      string
+	promAddr        string
+	promPushgateway string
+	promRemoteWrite string
+	promPushEvery   time.Duration
+	promJob         string
+	promInstance    string
+	urls            vegeta.URLNormalizer
+	influx          string
+	influxToken     string
+	statsd          string
+	dogstatsd       string
+	sinkEvery       time.Duration
+	trace           bool
+	otlpEndpoint    string
+	otlpFile        string
+	otlpHeaders     headers
+	dnsTTL          time.Duration
+	sessionTickets  bool
+	connectTo       map[string][]string
 }
 
 // attack validates the attack arguments, sets up the
 // required resources, launches the attack and writes the results
 func attack(opts *attackOpts) (err error) {
-	if opts.maxWorkers == vegeta.DefaultMaxWorkers && opts.rate.Freq == 0 {
-		return fmt.Errorf("-rate=0 requires setting -max-workers")
+	if opts.harTiming && opts.format != vegeta.HARTargetFormat {
+		return fmt.Errorf("-har-timing requires -format=%s", vegeta.HARTargetFormat)
 	}
 
-	if len(opts.resolvers) > 0 {
-		res, err := resolver.NewResolver(opts.resolvers)
-		if err != nil {
-			return err
-		}
-		net.DefaultResolver = res
+	if opts.logTiming && opts.format != vegeta.AccessLogTargetFormat {
+		return fmt.Errorf("-access-log-timing requires -format=%s", vegeta.AccessLogTargetFormat)
 	}
 
-	net.DefaultResolver.PreferGo = true
+	timing := opts.harTiming || opts.logTiming
+	if opts.maxWorkers == vegeta.DefaultMaxWorkers && opts.rate.Freq == 0 && !timing {
+		return fmt.Errorf("-rate=0 requires setting -max-workers")
+	}
+
+	// With an infinite rate there's always a tick due, so dropping or
+	// queueing them would only produce an endless stream of dropped ticks.
+	if opts.rate.Freq == 0 && opts.tickQueue != vegeta.BlockTicks && !timing {
+		return fmt.Errorf("-rate=0 requires -max-workers-policy=block")
+	}
 
 	files := map[string]io.Reader{}
 	for _, filename := range []string{opts.targetsf, opts.bodyf} {
@@ -149,21 +233,52 @@ func attack(opts *attackOpts) (err error) {
 		}
 	}
 
-	var (
-		tr       vegeta.Targeter
-		src      = files[opts.targetsf]
-		hdr      = opts.headers.Header
-		proxyHdr = opts.proxyHeaders.Header
-	)
+	atk, err := newAttacker(opts)
+	if err != nil {
+		return err
+	}
 
-	switch opts.format {
-	case vegeta.JSONTargetFormat:
-		tr = vegeta.NewJSONTargeter(src, body, hdr)
-	case vegeta.HTTPTargetFormat:
-		tr = vegeta.NewHTTPTargeter(src, body, hdr)
-	default:
-		return fmt.Errorf("format %q isn't one of [%s]",
-			opts.format, strings.Join(vegeta.TargetFormats, ", "))
+	if timing {
+		var (
+			targets []vegeta.Target
+			started []time.Time
+		)
+
+		if opts.harTiming {
+			targets, started, err = vegeta.ReadHARTargets(files[opts.targetsf], opts.har)
+		} else {
+			targets, started, err = vegeta.ReadAccessLogTargets(files[opts.targetsf], opts.accessLog)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for i := range targets {
+			if len(targets[i].Body) == 0 {
+				targets[i].Body = body
+			}
+			for k, vs := range opts.headers.Header {
+				// Recorded headers are canonical, while -header keeps keys as
+				// given, so they're merged case-insensitively.
+				key := k
+				for tk := range targets[i].Header {
+					if strings.EqualFold(tk, k) {
+						key = tk
+						break
+					}
+				}
+				targets[i].Header[key] = append(append([]string{}, vs...), targets[i].Header[key]...)
+			}
+		}
+
+		tr := vegeta.NewStaticTargeter(targets...)
+		return runAttack(atk, tr, vegeta.NewReplayPacer(started, 1), 0, opts)
+	}
+
+	tr, err := targeter(opts, files[opts.targetsf], body)
+	if err != nil {
+		return err
 	}
 
 	if !opts.lazy {
@@ -174,62 +289,269 @@ func attack(opts *attackOpts) (err error) {
 		tr = vegeta.NewStaticTargeter(targets...)
 	}
 
-	out, err := file(opts.outputf, true)
-	if err != nil {
-		return fmt.Errorf("error opening %s: %s", opts.outputf, err)
-	}
-	defer out.Close()
+	return runAttack(atk, tr, opts.rate, opts.duration, opts)
+}
 
-	tlsc, err := tlsConfig(opts.insecure, opts.certf, opts.keyf, opts.rootCerts)
-	if err != nil {
-		return err
+// targeter returns a Targeter that decodes targets from src in the format
+// given by the options.
+func targeter(opts *attackOpts, src io.Reader, body []byte) (vegeta.Targeter, error) {
+	hdr := opts.headers.Header
+	switch opts.format {
+	case vegeta.JSONTargetFormat:
+		return vegeta.NewJSONTargeter(src, body, hdr), nil
+	case vegeta.HTTPTargetFormat:
+		return vegeta.NewHTTPTargeter(src, body, hdr), nil
+	case vegeta.HARTargetFormat:
+		return vegeta.NewHARTargeter(src, body, hdr, opts.har), nil
+	case vegeta.CurlTargetFormat:
+		return vegeta.NewCurlTargeter(src, body, hdr), nil
+	case vegeta.AccessLogTargetFormat:
+		return vegeta.NewAccessLogTargeter(src, body, hdr, opts.accessLog), nil
+	default:
+		return nil, fmt.Errorf("format %q isn't one of [%s]",
+			opts.format, strings.Join(vegeta.TargetFormats, ", "))
 	}
+}
 
-	var pm *prom.Metrics
-	if opts.promAddr != "" {
-		pm = prom.NewMetrics()
+// newAttacker validates the Attacker options and returns a new Attacker
+// configured with them.
+func newAttacker(opts *attackOpts) (*vegeta.Attacker, error) {
+	if opts.laddrRotation != laddrRoundRobin && opts.laddrRotation != laddrRandom {
+		return nil, fmt.Errorf("-laddr-rotation %q isn't one of [%s, %s]", opts.laddrRotation, laddrRoundRobin, laddrRandom)
+	}
 
-		r := prometheus.NewRegistry()
-		if err := pm.Register(r); err != nil {
-			return fmt.Errorf("error registering prometheus metrics: %s", err)
+	if len(opts.resolvers) > 0 {
+		res, err := resolver.NewResolver(opts.resolvers)
+		if err != nil {
+			return nil, err
 		}
+		net.DefaultResolver = res
+	}
 
-		srv := http.Server{
-			Addr:    opts.promAddr,
-			Handler: prom.NewHandler(r, time.Now().UTC()),
-		}
+	net.DefaultResolver.PreferGo = true
 
-		defer srv.Close()
-		go srv.ListenAndServe()
+	tlsc, err := tlsConfig(opts.insecure, opts.certf, opts.keyf, opts.rootCerts)
+	if err != nil {
+		return nil, err
 	}
 
-	atk := vegeta.NewAttacker(
+	return vegeta.NewAttacker(
 		vegeta.Redirects(opts.redirects),
 		vegeta.Timeout(opts.timeout),
-		vegeta.LocalAddr(*opts.laddr.IPAddr),
+		vegeta.LocalAddrs(opts.laddrs, opts.laddrRotation == laddrRandom),
 		vegeta.TLSConfig(tlsc),
 		vegeta.Workers(opts.workers),
 		vegeta.MaxWorkers(opts.maxWorkers),
+		vegeta.TickQueue(opts.tickQueue),
 		vegeta.KeepAlive(opts.keepalive),
 		vegeta.Connections(opts.connections),
 		vegeta.MaxConnections(opts.maxConnections),
 		vegeta.HTTP2(opts.http2),
 		vegeta.H2C(opts.h2c),
 		vegeta.MaxBody(opts.maxBody),
+		vegeta.CaptureBodies(opts.capture),
+		vegeta.StopOn(opts.stops...),
 		vegeta.UnixSocket(opts.unixSocket),
-		vegeta.ProxyHeader(proxyHdr),
+		vegeta.ProxyHeader(opts.proxyHeaders.Header),
 		vegeta.ChunkedBody(opts.chunked),
 		vegeta.DNSCaching(opts.dnsTTL),
 		vegeta.ConnectTo(opts.connectTo),
 		vegeta.SessionTickets(opts.sessionTickets),
+		vegeta.TraceContext(opts.trace || opts.otlpEndpoint != "" || opts.otlpFile != ""),
+	), nil
+}
+
+// runAttack runs an attack with the given Attacker, Targeter and Pacer and
+// writes its results to the configured output until it's done or interrupted.
+func runAttack(atk *vegeta.Attacker, tr vegeta.Targeter, p vegeta.Pacer, du time.Duration, opts *attackOpts) error {
+	out, err := file(opts.outputf, true)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %s", opts.outputf, err)
+	}
+	defer out.Close()
+
+	var (
+		pm        *prom.Metrics
+		pushers   []prom.Pusher
+		observers []observer
+		sinks     []sink
 	)
 
-	res := atk.Attack(tr, opts.rate, opts.duration, opts.name)
+	if opts.promAddr != "" || opts.promPushgateway != "" || opts.promRemoteWrite != "" {
+		pm = prom.NewMetrics(prom.NormalizeURLs(&opts.urls))
+
+		r := prometheus.NewRegistry()
+		if err := pm.Register(r); err != nil {
+			return fmt.Errorf("error registering prometheus metrics: %s", err)
+		}
+
+		if err := r.Register(prom.NewAttackerCollector(atk)); err != nil {
+			return fmt.Errorf("error registering prometheus metrics: %s", err)
+		}
+
+		if opts.promAddr != "" {
+			srv := http.Server{
+				Addr:    opts.promAddr,
+				Handler: prom.NewHandler(r, time.Now().UTC()),
+			}
+
+			defer srv.Close()
+			go srv.ListenAndServe()
+		}
+
+		if (opts.promPushgateway != "" || opts.promRemoteWrite != "") && opts.promPushEvery <= 0 {
+			return fmt.Errorf("-prometheus-push-interval must be positive")
+		}
+
+		pushOpts := prom.PushOpts{Job: opts.promJob, Instance: opts.promInstance}
+		if pushOpts.Instance == "" {
+			pushOpts.Instance, _ = os.Hostname()
+		}
+
+		if opts.promPushgateway != "" {
+			pushOpts.URL = opts.promPushgateway
+			pushers = append(pushers, prom.NewPushgatewayPusher(r, pushOpts))
+		}
+
+		if opts.promRemoteWrite != "" {
+			pushOpts.URL = opts.promRemoteWrite
+			pushers = append(pushers, prom.NewRemoteWritePusher(r, pushOpts))
+		}
+
+		observers = append(observers, pm)
+	}
+
+	if opts.influx != "" {
+		var w io.Writer
+		if strings.HasPrefix(opts.influx, "http://") || strings.HasPrefix(opts.influx, "https://") {
+			hw := influx.NewHTTPWriter(opts.influx, opts.influxToken)
+			hw.Errors = func(err error) { log.Printf("error writing results to influx: %v", err) }
+			w = hw
+		} else {
+			f, err := file(opts.influx, true)
+			if err != nil {
+				return fmt.Errorf("error opening %s: %s", opts.influx, err)
+			}
+			defer f.Close()
+			w = bufio.NewWriter(f)
+		}
+		s := influx.NewSink(w, influx.Opts{Every: opts.sinkEvery, URLs: &opts.urls})
+		observers, sinks = append(observers, s), append(sinks, sink{"influx", s})
+	}
+
+	for _, addr := range []struct {
+		addr string
+		dog  bool
+	}{{opts.statsd, false}, {opts.dogstatsd, true}} {
+		if addr.addr == "" {
+			continue
+		}
+		s, err := statsd.Dial(addr.addr, statsd.Opts{DogStatsD: addr.dog, Every: opts.sinkEvery})
+		if err != nil {
+			return fmt.Errorf("error dialing statsd server: %w", err)
+		}
+		observers, sinks = append(observers, s), append(sinks, sink{"statsd", s})
+	}
+
+	otlpOpts := otlp.Opts{Errors: func(err error) { log.Printf("error exporting spans to otlp: %v", err) }}
+
+	if opts.otlpEndpoint != "" {
+		e := otlp.NewExporter(otlp.NewHTTPWriter(opts.otlpEndpoint, opts.otlpHeaders.Header), otlpOpts)
+		observers, sinks = append(observers, e), append(sinks, sink{"otlp", e})
+	}
+
+	if opts.otlpFile != "" {
+		f, err := file(opts.otlpFile, true)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %s", opts.otlpFile, err)
+		}
+		defer f.Close()
+		e := otlp.NewExporter(bufio.NewWriter(f), otlpOpts)
+		observers, sinks = append(observers, e), append(sinks, sink{"otlp", e})
+	}
+
+	// Metrics are pushed periodically during the attack and a final time
+	// once it's done, so that short-lived attacks are fully recorded.
+	ctx, cancel := context.WithCancel(context.Background())
+	pushErrs := make([]chan error, len(pushers))
+	for i, pusher := range pushers {
+		pushErrs[i] = make(chan error, 1)
+		go func(pusher prom.Pusher, errs chan<- error) {
+			errs <- prom.PushEvery(ctx, pusher, opts.promPushEvery, func(err error) {
+				log.Printf("error pushing prometheus metrics: %v", err)
+			})
+		}(pusher, pushErrs[i])
+	}
+
+	res := atk.Attack(tr, p, du, opts.name)
 	enc := vegeta.NewEncoder(out)
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
-	return processAttack(atk, res, enc, sig, pm)
+	err = processAttack(atk, res, enc, sig, observers...)
+
+	for _, s := range sinks {
+		if closeErr := s.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error writing results to %s: %w", s.name, closeErr)
+		}
+
+		if l, ok := s.Closer.(interface{ Late() uint64 }); ok && l.Late() > 0 {
+			log.Printf("%d results arrived after their -sink-interval window was written to %s and were skipped", l.Late(), s.name)
+		}
+	}
+
+	cancel()
+	for _, errs := range pushErrs {
+		if pushErr := <-errs; pushErr != nil && err == nil {
+			err = fmt.Errorf("error pushing prometheus metrics: %w", pushErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return stopError(atk.Tripped(res))
+}
+
+// Exit codes of attacks stopped early by a -stop-on condition.
+const (
+	exitErrorRatio          = 3
+	exitConsecutiveFailures = 4
+	exitLatencyCeiling      = 5
+)
+
+// stopError returns the error with which vegeta exits after an attack was
+// stopped by the given condition, with an exit code that names it.
+func stopError(c vegeta.StopCondition) error {
+	if c == nil {
+		return nil
+	}
+
+	err := &exitError{code: 1, err: fmt.Errorf("attack stopped: %s", c)}
+	switch c.(type) {
+	case vegeta.ErrorRatio:
+		err.code = exitErrorRatio
+	case vegeta.ConsecutiveFailures:
+		err.code = exitConsecutiveFailures
+	case vegeta.LatencyCeiling:
+		err.code = exitLatencyCeiling
+	}
+
+	return err
+}
+
+// An observer observes every result of an attack as it comes in, such as to
+// export metrics of them.
+type observer interface {
+	Observe(*vegeta.Result)
+}
+
+// A sink is an observer of results which must be closed after an attack to
+// finish writing them out.
+type sink struct {
+	name string
+	io.Closer
 }
 
 func processAttack(
@@ -237,7 +559,7 @@ func processAttack(
 	res <-chan *vegeta.Result,
 	enc vegeta.Encoder,
 	sig <-chan os.Signal,
-	pm *prom.Metrics,
+	observers ...observer,
 ) error {
 	for {
 		select {
@@ -251,8 +573,8 @@ func processAttack(
 				return nil
 			}
 
-			if pm != nil {
-				pm.Observe(r)
+			for _, o := range observers {
+				o.Observe(r)
 			}
 
 			if err := enc.Encode(r); err != nil {
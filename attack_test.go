@@ -3,11 +3,13 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"regexp"
 	"sync"
 	"testing"
 	"time"
@@ -36,6 +38,223 @@ func TestHeadersSet(t *testing.T) {
 	}
 }
 
+func TestLocalAddrsSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "127.0.0.1", want: "127.0.0.1"},
+		{in: "127.0.0.1, 127.0.0.2", want: "127.0.0.1,127.0.0.2"},
+		{in: "10.0.0.0/30", want: "10.0.0.1,10.0.0.2"},
+		{in: "10.0.0.4/31", want: "10.0.0.4,10.0.0.5"},
+		{in: "10.0.0.1/32,fd00::1/127", want: "10.0.0.1,fd00::,fd00::1"},
+		{in: "10.0.0.0/8", err: true},
+		{in: "10.0.0.0/15", err: true},
+		{in: "::/0", err: true},
+		{in: "", err: true},
+	} {
+		var l localAddrs
+		err := l.Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if got := l.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTickQueueFlagSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{in: "block", want: vegeta.BlockTicks},
+		{in: "drop", want: 0},
+		{in: "queue:10", want: 10},
+		{in: "queue:0", want: 0},
+		{in: "queue:-1", err: true},
+		{in: "queue:", err: true},
+		{in: "wait", err: true},
+	} {
+		var n int
+		err := (&tickQueueFlag{&n}).Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if n != tt.want {
+			t.Errorf("%q: got %d, want %d", tt.in, n, tt.want)
+		}
+	}
+}
+
+func TestBodyCaptureFlagSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want vegeta.BodyCapture
+		err  bool
+	}{
+		{in: "all", want: vegeta.BodyCapture{}},
+		{in: "errors", want: vegeta.BodyCapture{Errors: true}},
+		{in: "sample:0.1", want: vegeta.BodyCapture{Sample: 0.1}},
+		{in: "errors, first:3", want: vegeta.BodyCapture{Errors: true, FirstPerCode: 3}},
+		{in: "errors,sample:1,first:1", want: vegeta.BodyCapture{Errors: true, Sample: 1, FirstPerCode: 1}},
+		{in: "sample:0", err: true},
+		{in: "sample:2", err: true},
+		{in: "first:0", err: true},
+		{in: "first:", err: true},
+		{in: "none", err: true},
+	} {
+		var c vegeta.BodyCapture
+		err := (&bodyCaptureFlag{&c}).Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if c != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.in, c, tt.want)
+		}
+	}
+}
+
+func TestStopOnFlagSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want vegeta.StopCondition
+		err  bool
+	}{
+		{in: "error-ratio:0.1", want: vegeta.ErrorRatio{Max: 0.1, Window: 100}},
+		{in: "error-ratio:0.5/20", want: vegeta.ErrorRatio{Max: 0.5, Window: 20}},
+		{in: "consecutive-failures:10", want: vegeta.ConsecutiveFailures{N: 10}},
+		{in: "p99:500ms", want: vegeta.LatencyCeiling{Quantile: 0.99, Max: 500 * time.Millisecond, MinSamples: 100}},
+		{in: "p999:1s/1000", want: vegeta.LatencyCeiling{Quantile: 0.999, Max: time.Second, MinSamples: 1000}},
+		{in: "p05:1s", want: vegeta.LatencyCeiling{Quantile: 0.05, Max: time.Second, MinSamples: 100}},
+		{in: "p5:1s", err: true},
+		{in: "p100:1s", err: true},
+		{in: "error-ratio:1", err: true},
+		{in: "error-ratio:0.1/0", err: true},
+		{in: "consecutive-failures:0", err: true},
+		{in: "consecutive-failures:3/10", err: true},
+		{in: "p99:fast", err: true},
+		{in: "p0:1s", err: true},
+		{in: "p9.9:1s", err: true},
+		{in: "latency:1s", err: true},
+		{in: "error-ratio", err: true},
+	} {
+		var conds []vegeta.StopCondition
+		err := (&stopOnFlag{&conds}).Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if len(conds) != 1 || conds[0] != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.in, conds, tt.want)
+		}
+	}
+}
+
+func TestURLRulesFlagSet(t *testing.T) {
+	var rules []vegeta.URLRule
+	f := &urlRulesFlag{&rules}
+
+	for _, v := range []string{`/users/\d+=>/users/:id`, `/orders/([a-z]+)-\d+=>/orders/$1`} {
+		if err := f.Set(v); err != nil {
+			t.Fatalf("%q: %v", v, err)
+		}
+	}
+
+	if got, want := f.String(), `/users/\d+=>/users/:id, /orders/([a-z]+)-\d+=>/orders/$1`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	n := vegeta.URLNormalizer{Rules: rules}
+	if got, want := n.Normalize("http://goku/users/42/orders/eu-7"), "http://goku/users/:id/orders/eu"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, v := range []string{"/users", "=>/users/:id", "/users/(=>/users"} {
+		if err := f.Set(v); err == nil {
+			t.Errorf("%q: want error, got none", v)
+		}
+	}
+}
+
+func TestStopError(t *testing.T) {
+	for _, tt := range []struct {
+		cond vegeta.StopCondition
+		code int
+	}{
+		{vegeta.ErrorRatio{Max: 0.1, Window: 10}, exitErrorRatio},
+		{vegeta.ConsecutiveFailures{N: 3}, exitConsecutiveFailures},
+		{vegeta.LatencyCeiling{Quantile: 0.99, Max: time.Second}, exitLatencyCeiling},
+	} {
+		var exit *exitError
+		if err := stopError(tt.cond); !errors.As(err, &exit) {
+			t.Errorf("%s: got error %v, want an exit error", tt.cond, err)
+		} else if exit.code != tt.code {
+			t.Errorf("%s: got exit code %d, want %d", tt.cond, exit.code, tt.code)
+		}
+	}
+
+	if err := stopError(nil); err != nil {
+		t.Errorf("got error %v without a stop condition, want none", err)
+	}
+}
+
+func TestAccessLogFormatFlagSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "combined", want: vegeta.CombinedLogFormat.String()},
+		{in: "common", want: vegeta.CommonLogFormat.String()},
+		{in: `^(?P<request>[^|]+)\|`, want: `^(?P<request>[^|]+)\|`},
+		{in: `^(?P<method>\S+) (?P<path>\S+)`, want: `^(?P<method>\S+) (?P<path>\S+)`},
+		{in: `^(?P<method>\S+) (?P<xpath>\S+)`, err: true},
+		{in: `(`, err: true},
+	} {
+		var re *regexp.Regexp
+		err := (&accessLogFormatFlag{&re}).Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if re.String() != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.in, re, tt.want)
+		}
+	}
+}
+
 func decodeMetrics(buf bytes.Buffer) (vegeta.Metrics, error) {
 	var metrics vegeta.Metrics
 	dec := vegeta.NewDecoder(bufio.NewReader(&buf))
@@ -86,7 +305,7 @@ func TestAttackSignalOnce(t *testing.T) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		processAttack(atk, res, enc, sig, nil)
+		processAttack(atk, res, enc, sig)
 	}()
 
 	// Allow more than one request to have started before stopping.
@@ -139,7 +358,7 @@ func TestAttackSignalTwice(t *testing.T) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		processAttack(atk, res, enc, sig, nil)
+		processAttack(atk, res, enc, sig)
 	}()
 
 	// Exit as soon as possible.
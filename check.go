@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const checkUsage = `Usage: vegeta check [options] [<file>...]
+
+Checks the metrics of attack results against the given assertions, writing
+a pass/fail table of them to --output, and exits with a non-zero code when
+any of them fails.
+
+Assertions have the form <metric> <op> <value>, where <metric> is the dotted
+path of a field of the metrics JSON report (e.g. latencies.mean, success,
+bytes_in.total or status_codes.500), <op> is one of <, <=, >, >=, == or !=,
+and <value> is a number, a percentage (e.g. 99.9%) or a duration (e.g.
+300ms) for latencies, duration and wait. Any latency percentile can be
+given as latencies.pNN with at least two digits (e.g. latencies.p05,
+latencies.p99 or latencies.p999). Use latencies.max for the maximum.
+
+Arguments:
+  <file>  A file with vegeta attack results encoded with one of
+          the supported encodings (gob | json | csv) [default: stdin]
+
+Options:
+  --assert  An assertion on the metrics. Can be repeated multiple times.
+
+  --junit   A file to which a JUnit XML report is written, with an
+            assertion per test case. [default: none]
+
+  --output  Output file [default: stdout]
+
+Examples:
+  vegeta check -assert='latencies.p99 < 300ms' -assert='success >= 99.9%' results.bin
+  vegeta check -assert='status_codes.500 == 0' -junit=vegeta.xml results.bin
+`
+
+func checkCmd() command {
+	fs := flag.NewFlagSet("vegeta check", flag.ExitOnError)
+	var asserts assertions
+	fs.Var(&asserts, "assert", "Assertion on the metrics, e.g. 'latencies.p99 < 300ms'")
+	junit := fs.String("junit", "", "JUnit XML report file")
+	output := fs.String("output", "stdout", "Output file")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n", checkUsage)
+	}
+
+	return command{fs, func(args []string) error {
+		fs.Parse(args)
+		files := fs.Args()
+		if len(files) == 0 {
+			files = append(files, "stdin")
+		}
+		return check(files, asserts, *output, *junit)
+	}}
+}
+
+func check(files []string, asserts assertions, output, junit string) error {
+	if len(asserts) == 0 {
+		return errors.New("no assertions given with -assert")
+	}
+
+	dec, mc, err := decoder(files)
+	defer mc.Close()
+	if err != nil {
+		return err
+	}
+
+	var m vegeta.Metrics
+	for {
+		var r vegeta.Result
+		if err = dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		m.Add(&r)
+	}
+	m.Close()
+
+	checks, err := asserts.check(&m)
+	if err != nil {
+		return err
+	}
+
+	out, err := file(output, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err = writeChecks(out, checks); err != nil {
+		return err
+	}
+
+	if junit != "" {
+		f, err := file(junit, true)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err = writeJUnit(f, checks); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if !c.passed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return &exitError{code: 1, err: fmt.Errorf("%d of %d assertions failed", failed, len(checks))}
+	}
+
+	return nil
+}
+
+// assertion is an assertion on a metric, e.g. latencies.p99 < 300ms.
+type assertion struct {
+	expr   string
+	metric string
+	op     string
+	value  string
+}
+
+var assertionRe = regexp.MustCompile(`^\s*([\w.]+)\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`)
+
+func parseAssertion(expr string) (assertion, error) {
+	m := assertionRe.FindStringSubmatch(expr)
+	if m == nil {
+		return assertion{}, fmt.Errorf("bad assertion %q: doesn't match the <metric> <op> <value> format", expr)
+	}
+	return assertion{expr: strings.TrimSpace(expr), metric: m[1], op: m[2], value: m[3]}, nil
+}
+
+// check evaluates the assertion against the given metrics, returning its
+// actual value formatted for display, and whether it holds.
+func (a assertion) check(m *vegeta.Metrics, fields map[string]any) (actual string, passed bool, err error) {
+	got, isDuration, err := metric(m, fields, a.metric)
+	if err != nil {
+		return "", false, err
+	}
+
+	want, err := parseAssertionValue(a.value, isDuration)
+	if err != nil {
+		return "", false, fmt.Errorf("bad assertion %q: %w", a.expr, err)
+	}
+
+	if isDuration {
+		actual = time.Duration(got).String()
+	} else {
+		actual = strconv.FormatFloat(got, 'f', -1, 64)
+	}
+
+	switch a.op {
+	case "<":
+		passed = got < want
+	case "<=":
+		passed = got <= want
+	case ">":
+		passed = got > want
+	case ">=":
+		passed = got >= want
+	case "==":
+		passed = got == want
+	case "!=":
+		passed = got != want
+	}
+
+	return actual, passed, nil
+}
+
+// metric returns the value of the metric with the given dotted path and
+// whether it's a duration, from the given metrics and their JSON fields.
+func metric(m *vegeta.Metrics, fields map[string]any, path string) (float64, bool, error) {
+	isDuration := strings.HasPrefix(path, "latencies.") || path == "duration" || path == "wait"
+
+	if nth, ok := strings.CutPrefix(path, "latencies."); ok {
+		if q, ok := parseQuantile(nth); ok {
+			return float64(m.Latencies.Quantile(q)), true, nil
+		}
+	}
+
+	var v any = fields
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return 0, false, fmt.Errorf("unknown metric %q", path)
+		}
+
+		if v, ok = obj[key]; !ok {
+			// Status codes that weren't returned have a zero count.
+			if strings.HasPrefix(path, "status_codes.") {
+				return 0, false, nil
+			}
+			return 0, false, fmt.Errorf("unknown metric %q", path)
+		}
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false, fmt.Errorf("metric %q isn't a number", path)
+	}
+
+	f, err := n.Float64()
+	return f, isDuration, err
+}
+
+// parseAssertionValue parses the value of an assertion, as a duration in
+// nanoseconds for duration metrics.
+func parseAssertionValue(v string, isDuration bool) (float64, error) {
+	if isDuration {
+		if d, err := time.ParseDuration(v); err == nil {
+			return float64(d), nil
+		}
+	}
+
+	if pct, ok := strings.CutSuffix(v, "%"); ok {
+		f, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad percentage %q", v)
+		}
+		return f / 100, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", v)
+	}
+
+	return f, nil
+}
+
+// assertions implements the flag.Value interface for repeated assertions.
+type assertions []assertion
+
+func (as *assertions) Set(v string) error {
+	a, err := parseAssertion(v)
+	if err != nil {
+		return err
+	}
+	*as = append(*as, a)
+	return nil
+}
+
+func (as assertions) String() string {
+	exprs := make([]string, 0, len(as))
+	for _, a := range as {
+		exprs = append(exprs, a.expr)
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// checked is an evaluated assertion.
+type checked struct {
+	assertion
+	actual string
+	passed bool
+}
+
+func (as assertions) check(m *vegeta.Metrics) ([]checked, error) {
+	// Metrics are looked up by the paths of their JSON report fields.
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	checks := make([]checked, 0, len(as))
+	for _, a := range as {
+		actual, passed, err := a.check(m, fields)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, checked{assertion: a, actual: actual, passed: passed})
+	}
+
+	return checks, nil
+}
+
+func writeChecks(w io.Writer, checks []checked) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
+	fmt.Fprintf(tw, "Assertion\tActual\tResult\n")
+
+	passed := 0
+	for _, c := range checks {
+		result := "FAIL"
+		if c.passed {
+			result = "PASS"
+			passed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.expr, c.actual, result)
+	}
+
+	fmt.Fprintf(tw, "\n%d of %d assertions passed\n", passed, len(checks))
+	return tw.Flush()
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+func writeJUnit(w io.Writer, checks []checked) error {
+	suite := junitTestSuite{Name: "vegeta check", Tests: len(checks)}
+	for _, c := range checks {
+		tc := junitTestCase{Name: c.expr, ClassName: "vegeta.check." + c.metric}
+		if !c.passed {
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s is %s", c.metric, c.actual),
+				Type:    "AssertionFailed",
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
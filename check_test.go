@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestParseAssertion(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want assertion
+		err  bool
+	}{
+		{in: "latencies.p99 < 300ms", want: assertion{expr: "latencies.p99 < 300ms", metric: "latencies.p99", op: "<", value: "300ms"}},
+		{in: " success>=0.999 ", want: assertion{expr: "success>=0.999", metric: "success", op: ">=", value: "0.999"}},
+		{in: "status_codes.500 == 0", want: assertion{expr: "status_codes.500 == 0", metric: "status_codes.500", op: "==", value: "0"}},
+		{in: "rate != 10", want: assertion{expr: "rate != 10", metric: "rate", op: "!=", value: "10"}},
+		{in: "success", err: true},
+		{in: "success => 1", err: true},
+		{in: "success >= 0.9 or rate > 1", err: true},
+	} {
+		got, err := parseAssertion(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuantile(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{in: "p50", want: 0.5, ok: true},
+		{in: "p99", want: 0.99, ok: true},
+		{in: "p999", want: 0.999, ok: true},
+		{in: "p05", want: 0.05, ok: true},
+		{in: "p10", want: 0.1, ok: true},
+		{in: "p105", want: 0.105, ok: true},
+		{in: "p5"},
+		{in: "p100"},
+		{in: "p1000"},
+		{in: "p00"},
+		{in: "p"},
+		{in: "p9.9"},
+		{in: "99"},
+	} {
+		got, ok := parseQuantile(tt.in)
+		if ok != tt.ok || got != tt.want && tt.ok {
+			t.Errorf("%q: got (%v, %v), want (%v, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAssertionsCheck(t *testing.T) {
+	t.Parallel()
+
+	var m vegeta.Metrics
+	began := time.Unix(1000, 0)
+	for i := 0; i < 100; i++ {
+		r := vegeta.Result{
+			Code:      200,
+			Timestamp: began.Add(time.Duration(i) * 10 * time.Millisecond),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+			BytesIn:   10,
+		}
+		if i%10 == 0 {
+			r.Code, r.Error = 500, "500 Internal Server Error"
+		}
+		m.Add(&r)
+	}
+	m.Close()
+
+	for _, tt := range []struct {
+		expr   string
+		actual string
+		passed bool
+		err    bool
+	}{
+		{expr: "latencies.max < 300ms", actual: "100ms", passed: true},
+		{expr: "latencies.p99 < 50ms", actual: m.Latencies.P99.String(), passed: false},
+		{expr: "latencies.p50 <= 1s", actual: m.Latencies.P50.String(), passed: true},
+		{expr: "latencies.99th < 1s", actual: m.Latencies.P99.String(), passed: true},
+		{expr: "success >= 0.9", actual: "0.9", passed: true},
+		{expr: "success >= 99.9%", actual: "0.9", passed: false},
+		{expr: "status_codes.500 == 10", actual: "10", passed: true},
+		{expr: "status_codes.404 == 0", actual: "0", passed: true},
+		{expr: "status_codes.200 > 90", actual: "90", passed: false},
+		{expr: "bytes_in.total == 1000", actual: "1000", passed: true},
+		{expr: "requests != 100", actual: "100", passed: false},
+		{expr: "duration < 1s", actual: "990ms", passed: true},
+		{expr: "latencies.p99 < fast", err: true},
+		{expr: "errors == 1", err: true},
+		{expr: "latency.p99 < 1s", err: true},
+	} {
+		a, err := parseAssertion(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checks, err := assertions{a}.check(&m)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.expr)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+		} else if c := checks[0]; c.actual != tt.actual || c.passed != tt.passed {
+			t.Errorf("%q: got %s and passed %t, want %s and passed %t", tt.expr, c.actual, c.passed, tt.actual, tt.passed)
+		}
+	}
+}
+
+func TestWriteChecks(t *testing.T) {
+	t.Parallel()
+
+	checks := []checked{
+		{assertion: assertion{expr: "latencies.p99 < 300ms", metric: "latencies.p99"}, actual: "120ms", passed: true},
+		{assertion: assertion{expr: "success >= 0.999", metric: "success"}, actual: "0.9", passed: false},
+	}
+
+	var buf bytes.Buffer
+	if err := writeChecks(&buf, checks); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"Assertion              Actual  Result",
+		"latencies.p99 < 300ms  120ms   PASS",
+		"success >= 0.999       0.9     FAIL",
+		"",
+		"1 of 2 assertions passed",
+		"",
+	}, "\n")
+
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := writeJUnit(&buf, checks); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitTestSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.TestCases) != 2 {
+		t.Fatalf("got %d tests and %d failures, want 2 and 1", suite.Tests, suite.Failures)
+	}
+
+	if tc := suite.TestCases[0]; tc.Name != checks[0].expr || tc.Failure != nil {
+		t.Errorf("got test case %+v, want a passed %q", tc, checks[0].expr)
+	}
+
+	if tc := suite.TestCases[1]; tc.Failure == nil || tc.Failure.Message != "success is 0.9" {
+		t.Errorf("got test case %+v, want a failed %q", tc, checks[1].expr)
+	}
+}
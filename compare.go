@@ -0,0 +1,630 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const compareUsage = `Usage: vegeta compare [options] <base> <candidate>
+
+Compares the results of a candidate attack against the ones of a base attack.
+It computes the deltas of every metric and latency percentile, tests whether
+the latency distributions differ significantly with the Mann-Whitney U and
+Kolmogorov-Smirnov tests, estimates bootstrap confidence intervals of the
+deltas of latency percentiles, and flags the metrics which regressed beyond
+the given tolerance, in which case it exits with a non-zero code.
+
+Arguments:
+  <base>       A file with the vegeta attack results of the base run,
+               encoded with one of the supported encodings (gob | json | csv)
+  <candidate>  A file with the vegeta attack results of the candidate run
+
+Options:
+  --type       Output type (text | json | markdown) [default: text]
+
+  --tolerance  Relative change of a metric in its worse direction, such as
+               higher latencies or lower success, beyond which it's flagged
+               as a regression, given as a ratio or a percentage. [default: 5%]
+
+  --alpha      Significance level of the statistical tests, and one minus
+               the confidence level of the bootstrap intervals. [default: 0.05]
+
+  --resamples  Number of bootstrap resamples. [default: 1000]
+
+  --output     Output file [default: stdout]
+
+Examples:
+  vegeta compare base.bin candidate.bin
+  vegeta compare -type=markdown -tolerance=10% base.bin candidate.bin > comparison.md
+`
+
+func compareCmd() command {
+	fs := flag.NewFlagSet("vegeta compare", flag.ExitOnError)
+	typ := fs.String("type", "text", "Output type [text, json, markdown]")
+	tolerance := fs.String("tolerance", "5%", "Relative change beyond which a metric is flagged as a regression")
+	alpha := fs.Float64("alpha", 0.05, "Significance level")
+	resamples := fs.Int("resamples", 1000, "Number of bootstrap resamples")
+	output := fs.String("output", "stdout", "Output file")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n", compareUsage)
+	}
+
+	return command{fs, func(args []string) error {
+		fs.Parse(args)
+		if fs.NArg() != 2 {
+			return errors.New("compare needs a base and a candidate results file")
+		}
+
+		tol, err := parseTolerance(*tolerance)
+		if err != nil {
+			return err
+		}
+
+		opts := compareOpts{tolerance: tol, alpha: *alpha, resamples: *resamples}
+		return compare(fs.Arg(0), fs.Arg(1), *typ, *output, opts)
+	}}
+}
+
+type compareOpts struct {
+	tolerance float64
+	alpha     float64
+	resamples int
+}
+
+func compare(basef, candf, typ, output string, opts compareOpts) error {
+	var write func(io.Writer, *comparison) error
+	switch typ {
+	case "text":
+		write = writeComparisonText
+	case "json":
+		write = writeComparisonJSON
+	case "markdown":
+		write = writeComparisonMarkdown
+	default:
+		return fmt.Errorf("unknown compare output type: %q", typ)
+	}
+
+	if opts.alpha <= 0 || opts.alpha >= 1 {
+		return errors.New("-alpha must be between 0 and 1")
+	} else if opts.resamples <= 0 {
+		return errors.New("-resamples must be bigger than zero")
+	}
+
+	base, err := readRun(basef)
+	if err != nil {
+		return err
+	}
+
+	cand, err := readRun(candf)
+	if err != nil {
+		return err
+	}
+
+	c := compareRuns(base, cand, opts)
+
+	out, err := file(output, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err = write(out, c); err != nil {
+		return err
+	}
+
+	if c.Regressions > 0 {
+		return &exitError{code: 1, err: fmt.Errorf("%d metrics regressed beyond the %g%% tolerance", c.Regressions, c.Tolerance*100)}
+	}
+
+	return nil
+}
+
+// parseTolerance parses a tolerance given as a ratio or a percentage.
+func parseTolerance(v string) (float64, error) {
+	s, pct := strings.CutSuffix(v, "%")
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("bad tolerance %q", v)
+	}
+
+	if pct {
+		f /= 100
+	}
+
+	return f, nil
+}
+
+// run holds the metrics of the results of an attack along with their sorted
+// latencies, which the statistical tests need in full.
+type run struct {
+	metrics   vegeta.Metrics
+	latencies []float64
+}
+
+func readRun(filename string) (*run, error) {
+	dec, mc, err := decoder([]string{filename})
+	defer mc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var r run
+	for {
+		var res vegeta.Result
+		if err = dec.Decode(&res); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", filename, err)
+		}
+
+		r.metrics.Add(&res)
+		r.latencies = append(r.latencies, float64(res.Latency))
+	}
+	r.metrics.Close()
+
+	if len(r.latencies) == 0 {
+		return nil, fmt.Errorf("no results in %s", filename)
+	}
+
+	sort.Float64s(r.latencies)
+	return &r, nil
+}
+
+// comparison is the comparison of a candidate run against a base run.
+type comparison struct {
+	Tolerance         float64              `json:"tolerance"`
+	Alpha             float64              `json:"alpha"`
+	Metrics           []metricDelta        `json:"metrics"`
+	MannWhitneyU      significanceTest     `json:"mann_whitney_u"`
+	KolmogorovSmirnov significanceTest     `json:"kolmogorov_smirnov"`
+	Percentiles       []percentileInterval `json:"percentiles"`
+	Regressions       int                  `json:"regressions"`
+}
+
+// metricDelta is the change of a metric from the base to the candidate run.
+// Durations are in nanoseconds.
+type metricDelta struct {
+	Metric     string   `json:"metric"`
+	Base       float64  `json:"base"`
+	Candidate  float64  `json:"candidate"`
+	Delta      float64  `json:"delta"`
+	DeltaRatio *float64 `json:"delta_ratio"` // Undefined when only the base is zero.
+	Regression bool     `json:"regression"`
+
+	duration bool
+}
+
+// significanceTest is the result of a test of whether the latency
+// distributions of both runs differ.
+type significanceTest struct {
+	Statistic   float64 `json:"statistic"`
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"`
+}
+
+// percentileInterval is the bootstrap confidence interval of the delta of a
+// latency percentile, in nanoseconds.
+type percentileInterval struct {
+	Quantile float64 `json:"quantile"`
+	Delta    float64 `json:"delta"`
+	Low      float64 `json:"low"`
+	High     float64 `json:"high"`
+}
+
+// Directions in which a change of a metric is a regression.
+const (
+	neutral = iota
+	higherIsWorse
+	lowerIsWorse
+)
+
+// bootstrapQuantiles are the latency percentiles whose deltas are bootstrapped.
+var bootstrapQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+func compareRuns(base, cand *run, opts compareOpts) *comparison {
+	c := comparison{Tolerance: opts.tolerance, Alpha: opts.alpha}
+
+	add := func(metric string, b, k float64, duration bool, direction int) {
+		d := metricDelta{Metric: metric, Base: b, Candidate: k, Delta: k - b, duration: duration}
+		if b != 0 || k == 0 {
+			r := 0.0
+			if b != 0 {
+				r = d.Delta / math.Abs(b)
+			}
+			d.DeltaRatio = &r
+		}
+
+		switch {
+		case direction == neutral || d.Delta == 0:
+		case d.DeltaRatio == nil:
+			d.Regression = direction == higherIsWorse && d.Delta > 0 || direction == lowerIsWorse && d.Delta < 0
+		case direction == higherIsWorse:
+			d.Regression = *d.DeltaRatio > opts.tolerance
+		case direction == lowerIsWorse:
+			d.Regression = *d.DeltaRatio < -opts.tolerance
+		}
+
+		if d.Regression {
+			c.Regressions++
+		}
+
+		c.Metrics = append(c.Metrics, d)
+	}
+
+	bm, cm := &base.metrics, &cand.metrics
+	for _, l := range []struct {
+		name string
+		b, c time.Duration
+	}{
+		{"min", bm.Latencies.Min, cm.Latencies.Min},
+		{"mean", bm.Latencies.Mean, cm.Latencies.Mean},
+		{"50th", bm.Latencies.P50, cm.Latencies.P50},
+		{"90th", bm.Latencies.P90, cm.Latencies.P90},
+		{"95th", bm.Latencies.P95, cm.Latencies.P95},
+		{"99th", bm.Latencies.P99, cm.Latencies.P99},
+		{"max", bm.Latencies.Max, cm.Latencies.Max},
+	} {
+		add("latencies."+l.name, float64(l.b), float64(l.c), true, higherIsWorse)
+	}
+
+	// The total latency grows with the number of requests.
+	add("latencies.total", float64(bm.Latencies.Total), float64(cm.Latencies.Total), true, neutral)
+
+	add("requests", float64(bm.Requests), float64(cm.Requests), false, neutral)
+	add("rate", bm.Rate, cm.Rate, false, neutral)
+	add("throughput", bm.Throughput, cm.Throughput, false, lowerIsWorse)
+	add("success", bm.Success, cm.Success, false, lowerIsWorse)
+	add("duration", float64(bm.Duration), float64(cm.Duration), true, neutral)
+	add("wait", float64(bm.Wait), float64(cm.Wait), true, higherIsWorse)
+	add("bytes_in.total", float64(bm.BytesIn.Total), float64(cm.BytesIn.Total), false, neutral)
+	add("bytes_in.mean", bm.BytesIn.Mean, cm.BytesIn.Mean, false, neutral)
+	add("bytes_out.total", float64(bm.BytesOut.Total), float64(cm.BytesOut.Total), false, neutral)
+	add("bytes_out.mean", bm.BytesOut.Mean, cm.BytesOut.Mean, false, neutral)
+	// Errors only holds the distinct error messages, so compare the number of failed requests.
+	add("failures", failures(bm), failures(cm), false, higherIsWorse)
+
+	codes := map[string]struct{}{}
+	for code := range bm.StatusCodes {
+		codes[code] = struct{}{}
+	}
+	for code := range cm.StatusCodes {
+		codes[code] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	slices.Sort(sorted)
+
+	for _, code := range sorted {
+		add("status_codes."+code, float64(bm.StatusCodes[code]), float64(cm.StatusCodes[code]), false, neutral)
+	}
+
+	u, p := mannWhitneyU(base.latencies, cand.latencies)
+	c.MannWhitneyU = significanceTest{Statistic: u, PValue: p, Significant: p < opts.alpha}
+
+	d, p := kolmogorovSmirnov(base.latencies, cand.latencies)
+	c.KolmogorovSmirnov = significanceTest{Statistic: d, PValue: p, Significant: p < opts.alpha}
+
+	// A fixed seed keeps the intervals of the same runs reproducible.
+	rng := rand.New(rand.NewPCG(1, 2))
+	c.Percentiles = bootstrapPercentiles(base.latencies, cand.latencies, bootstrapQuantiles, opts.resamples, opts.alpha, rng)
+
+	return &c
+}
+
+// mannWhitneyU returns the U statistic of the first sample in the
+// Mann-Whitney U test of the given samples, and its two-sided p-value using
+// the normal approximation corrected for ties.
+func mannWhitneyU(a, b []float64) (u, p float64) {
+	type obs struct {
+		v     float64
+		first bool
+	}
+
+	all := make([]obs, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	var (
+		n1, n2 = float64(len(a)), float64(len(b))
+		n      = n1 + n2
+		ranks  float64 // Sum of the ranks of the first sample.
+		ties   float64 // Sum of t^3 - t over groups of t ties.
+	)
+
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+
+		// Tied observations get the mean of their ranks, which are 1-based.
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				ranks += rank
+			}
+		}
+
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u = ranks - n1*(n1+1)/2
+	mean := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 || math.IsNaN(sigma) {
+		return u, 1
+	}
+
+	z := (math.Abs(u-mean) - 0.5) / sigma
+	return u, math.Min(1, math.Erfc(math.Max(z, 0)/math.Sqrt2))
+}
+
+// kolmogorovSmirnov returns the D statistic of the two-sample
+// Kolmogorov-Smirnov test of the given sorted samples, and its asymptotic
+// p-value.
+func kolmogorovSmirnov(a, b []float64) (d, p float64) {
+	var (
+		n1, n2 = float64(len(a)), float64(len(b))
+		i, j   int
+	)
+
+	for i < len(a) && j < len(b) {
+		v := math.Min(a[i], b[j])
+		for i < len(a) && a[i] == v {
+			i++
+		}
+		for j < len(b) && b[j] == v {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/n1-float64(j)/n2))
+	}
+
+	ne := math.Sqrt(n1 * n2 / (n1 + n2))
+	lambda := (ne + 0.12 + 0.11/ne) * d
+	return d, ksProb(lambda)
+}
+
+// ksProb returns the complementary cumulative Kolmogorov distribution at
+// the given point.
+func ksProb(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+
+	var (
+		sum  float64
+		sign = 1.0
+	)
+
+	for j := 1; j <= 100; j++ {
+		term := sign * 2 * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-10 {
+			break
+		}
+		sign = -sign
+	}
+
+	return math.Max(0, math.Min(1, sum))
+}
+
+// bootstrapPercentiles returns the deltas of the given quantiles from the
+// sorted sample a to the sorted sample b, with their 1-alpha bootstrap
+// confidence intervals.
+func bootstrapPercentiles(a, b []float64, qs []float64, resamples int, alpha float64, rng *rand.Rand) []percentileInterval {
+	deltas := make([][]float64, len(qs))
+	ca, cb := make([]int, len(a)), make([]int, len(b))
+	qa, qb := make([]float64, len(qs)), make([]float64, len(qs))
+
+	for i := 0; i < resamples; i++ {
+		resampleQuantiles(a, qs, ca, qa, rng)
+		resampleQuantiles(b, qs, cb, qb, rng)
+		for k := range qs {
+			deltas[k] = append(deltas[k], qb[k]-qa[k])
+		}
+	}
+
+	intervals := make([]percentileInterval, len(qs))
+	for k, q := range qs {
+		sort.Float64s(deltas[k])
+		lo := int(math.Floor(alpha / 2 * float64(resamples)))
+		hi := int(math.Ceil((1-alpha/2)*float64(resamples))) - 1
+		intervals[k] = percentileInterval{
+			Quantile: q,
+			Delta:    quantile(b, q) - quantile(a, q),
+			Low:      deltas[k][max(lo, 0)],
+			High:     deltas[k][min(hi, resamples-1)],
+		}
+	}
+
+	return intervals
+}
+
+// resampleQuantiles writes to out the given quantiles of a resample with
+// replacement of the given sorted sample, using counts as scratch space.
+// Counting how many times each index is drawn avoids sorting the resample.
+func resampleQuantiles(sorted []float64, qs []float64, counts []int, out []float64, rng *rand.Rand) {
+	for i := range counts {
+		counts[i] = 0
+	}
+
+	for range sorted {
+		counts[rng.IntN(len(sorted))]++
+	}
+
+	seen, k := 0, 0
+	for i, n := range counts {
+		seen += n
+		for k < len(qs) && seen >= rank(qs[k], len(sorted)) {
+			out[k] = sorted[i]
+			k++
+		}
+	}
+}
+
+// quantile returns the given quantile of the sorted sample with the nearest
+// rank method.
+func quantile(sorted []float64, q float64) float64 {
+	return sorted[rank(q, len(sorted))-1]
+}
+
+// rank returns the 1-based nearest rank of the given quantile in a sample
+// of size n.
+func rank(q float64, n int) int {
+	return max(1, min(n, int(math.Ceil(q*float64(n)))))
+}
+
+func writeComparisonText(w io.Writer, c *comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
+	fmt.Fprintf(tw, "Metric\tBase\tCandidate\tDelta\tDelta %%\t\n")
+	for _, m := range c.Metrics {
+		mark := ""
+		if m.Regression {
+			mark = "REGRESSION"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", m.Metric, m.format(m.Base), m.format(m.Candidate),
+			m.formatDelta(), m.formatRatio(), mark)
+	}
+
+	fmt.Fprintf(tw, "\nLatency distributions\tStatistic\tp-value\t\n")
+	for _, t := range []struct {
+		name string
+		significanceTest
+	}{
+		{"Mann-Whitney U", c.MannWhitneyU},
+		{"Kolmogorov-Smirnov", c.KolmogorovSmirnov},
+	} {
+		fmt.Fprintf(tw, "%s\t%.4g\t%.4g\t%s\n", t.name, t.Statistic, t.PValue, t.verdict())
+	}
+
+	fmt.Fprintf(tw, "\nPercentile\tDelta\t%g%% CI\t\n", (1-c.Alpha)*100)
+	for _, p := range c.Percentiles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", p.name(), signedDuration(p.Delta), p.interval())
+	}
+
+	fmt.Fprintf(tw, "\n%d regressions beyond the %g%% tolerance\n", c.Regressions, c.Tolerance*100)
+	return tw.Flush()
+}
+
+func writeComparisonJSON(w io.Writer, c *comparison) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+func writeComparisonMarkdown(w io.Writer, c *comparison) error {
+	var b strings.Builder
+
+	b.WriteString("| Metric | Base | Candidate | Delta | Delta % | |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: | --- |\n")
+	for _, m := range c.Metrics {
+		mark := ""
+		if m.Regression {
+			mark = ":warning: regression"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s | %s |\n", m.Metric, m.format(m.Base), m.format(m.Candidate),
+			m.formatDelta(), m.formatRatio(), mark)
+	}
+
+	b.WriteString("\n| Latency distributions | Statistic | p-value | |\n")
+	b.WriteString("| --- | ---: | ---: | --- |\n")
+	fmt.Fprintf(&b, "| Mann-Whitney U | %.4g | %.4g | %s |\n", c.MannWhitneyU.Statistic, c.MannWhitneyU.PValue, c.MannWhitneyU.verdict())
+	fmt.Fprintf(&b, "| Kolmogorov-Smirnov | %.4g | %.4g | %s |\n", c.KolmogorovSmirnov.Statistic, c.KolmogorovSmirnov.PValue, c.KolmogorovSmirnov.verdict())
+
+	fmt.Fprintf(&b, "\n| Percentile | Delta | %g%% CI |\n", (1-c.Alpha)*100)
+	b.WriteString("| --- | ---: | ---: |\n")
+	for _, p := range c.Percentiles {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", p.name(), signedDuration(p.Delta), p.interval())
+	}
+
+	fmt.Fprintf(&b, "\n**%d regressions** beyond the %g%% tolerance.\n", c.Regressions, c.Tolerance*100)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m metricDelta) format(v float64) string {
+	if m.duration {
+		return time.Duration(math.Round(v)).String()
+	}
+	return formatFloat(v)
+}
+
+func (m metricDelta) formatDelta() string {
+	if m.duration {
+		return signedDuration(m.Delta)
+	}
+
+	s := formatFloat(m.Delta)
+	if m.Delta > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+func (m metricDelta) formatRatio() string {
+	if m.DeltaRatio == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.2f%%", *m.DeltaRatio*100)
+}
+
+func (t significanceTest) verdict() string {
+	if t.Significant {
+		return "significant"
+	}
+	return "not significant"
+}
+
+func (p percentileInterval) name() string {
+	return fmt.Sprintf("p%g", math.Round(p.Quantile*1e4)/100)
+}
+
+func (p percentileInterval) interval() string {
+	return "[" + signedDuration(p.Low) + ", " + signedDuration(p.High) + "]"
+}
+
+// formatFloat formats integral values in full, and others with up to six
+// significant digits.
+func formatFloat(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+func signedDuration(ns float64) string {
+	s := time.Duration(math.Round(ns)).String()
+	if ns > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// failures returns the number of failed requests in the given metrics.
+func failures(m *vegeta.Metrics) float64 {
+	return math.Round(float64(m.Requests) * (1 - m.Success))
+}
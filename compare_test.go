@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestParseTolerance(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{in: "5%", want: 0.05},
+		{in: "0.1", want: 0.1},
+		{in: "0", want: 0},
+		{in: "-1%", err: true},
+		{in: "five", err: true},
+	} {
+		got, err := parseTolerance(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: want error, got none", tt.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%q: got %g, want %g", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		a, b []float64
+		u, p float64
+	}{
+		// Values as computed by scipy.stats.mannwhitneyu with the asymptotic method.
+		{a: []float64{1, 2, 3}, b: []float64{4, 5, 6}, u: 0, p: 0.080856},
+		{a: []float64{4, 5, 6}, b: []float64{1, 2, 3}, u: 9, p: 0.080856},
+		{a: []float64{1, 2, 2, 3, 5}, b: []float64{2, 4, 6, 7}, u: 4, p: 0.170609},
+		{a: []float64{1, 1, 1}, b: []float64{1, 1}, u: 3, p: 1},
+	} {
+		u, p := mannWhitneyU(tt.a, tt.b)
+		if u != tt.u || math.Abs(p-tt.p) > 1e-5 {
+			t.Errorf("%v, %v: got U=%g p=%g, want U=%g p=%g", tt.a, tt.b, u, p, tt.u, tt.p)
+		}
+	}
+}
+
+func TestKolmogorovSmirnov(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		a, b []float64
+		d    float64
+		p    func(float64) bool
+	}{
+		{a: []float64{1, 2, 3}, b: []float64{1, 2, 3}, d: 0, p: func(p float64) bool { return p == 1 }},
+		{a: []float64{1, 2, 3}, b: []float64{4, 5, 6}, d: 1, p: func(p float64) bool { return p < 0.1 }},
+		{a: []float64{1, 2, 3, 4}, b: []float64{3, 4, 5, 6}, d: 0.5, p: func(p float64) bool { return p > 0.5 }},
+	} {
+		d, p := kolmogorovSmirnov(tt.a, tt.b)
+		if d != tt.d || !tt.p(p) {
+			t.Errorf("%v, %v: got D=%g p=%g, want D=%g", tt.a, tt.b, d, p, tt.d)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	a, b := make([]float64, 1000), make([]float64, 1000)
+	for i := range a {
+		a[i], b[i] = rng.NormFloat64(), rng.NormFloat64()+0.5
+	}
+	sort.Float64s(a)
+	sort.Float64s(b)
+
+	if _, p := kolmogorovSmirnov(a, b); p > 1e-6 {
+		t.Errorf("got p=%g for shifted distributions, want it significant", p)
+	}
+}
+
+func TestBootstrapPercentiles(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	qs := []float64{0.5, 0.99}
+
+	a, b := []float64{1, 1, 1, 1}, []float64{3, 3, 3}
+	for _, p := range bootstrapPercentiles(a, b, qs, 100, 0.05, rng) {
+		if p.Delta != 2 || p.Low != 2 || p.High != 2 {
+			t.Errorf("p%g: got %+v, want a delta of 2 without variance", p.Quantile, p)
+		}
+	}
+
+	a, b = make([]float64, 500), make([]float64, 500)
+	for i := range a {
+		a[i], b[i] = rng.ExpFloat64(), rng.ExpFloat64()+1
+	}
+	sort.Float64s(a)
+	sort.Float64s(b)
+
+	for _, p := range bootstrapPercentiles(a, b, qs, 200, 0.05, rng) {
+		if p.Low > p.Delta || p.Delta > p.High || p.Low >= p.High {
+			t.Errorf("p%g: got interval [%g, %g] around %g", p.Quantile, p.Low, p.High, p.Delta)
+		}
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	t.Parallel()
+
+	newRun := func(latency time.Duration, failures int) *run {
+		var r run
+		for i := 0; i < 100; i++ {
+			res := vegeta.Result{
+				Code:      200,
+				Timestamp: time.Unix(0, 0).Add(time.Duration(i) * 10 * time.Millisecond),
+				Latency:   latency + time.Duration(i)*time.Microsecond,
+			}
+			if i < failures {
+				res.Code, res.Error = 500, "500 Internal Server Error"
+			}
+			r.metrics.Add(&res)
+			r.latencies = append(r.latencies, float64(res.Latency))
+		}
+		r.metrics.Close()
+		sort.Float64s(r.latencies)
+		return &r
+	}
+
+	opts := compareOpts{tolerance: 0.05, alpha: 0.05, resamples: 100}
+
+	same := compareRuns(newRun(10*time.Millisecond, 0), newRun(10*time.Millisecond, 0), opts)
+	if same.Regressions != 0 || same.MannWhitneyU.Significant || same.KolmogorovSmirnov.Significant {
+		t.Errorf("got %d regressions comparing the same runs, want none", same.Regressions)
+	}
+
+	c := compareRuns(newRun(10*time.Millisecond, 0), newRun(20*time.Millisecond, 10), opts)
+	if !c.MannWhitneyU.Significant || !c.KolmogorovSmirnov.Significant {
+		t.Errorf("got %+v and %+v, want significant differences", c.MannWhitneyU, c.KolmogorovSmirnov)
+	}
+
+	regressed := map[string]bool{}
+	for _, m := range c.Metrics {
+		regressed[m.Metric] = m.Regression
+		if m.Metric == "failures" && (m.Base != 0 || m.Candidate != 10) {
+			t.Errorf("failures: got %g -> %g, want 0 -> 10", m.Base, m.Candidate)
+		}
+	}
+
+	for metric, want := range map[string]bool{
+		"latencies.50th":   true,
+		"latencies.99th":   true,
+		"success":          true,
+		"throughput":       true,
+		"failures":         true,
+		"latencies.total":  false,
+		"requests":         false,
+		"status_codes.500": false,
+	} {
+		if got, ok := regressed[metric]; !ok || got != want {
+			t.Errorf("%s: got regression %t (found: %t), want %t", metric, got, ok, want)
+		}
+	}
+
+	for _, p := range c.Percentiles {
+		if p.Low < float64(9*time.Millisecond) {
+			t.Errorf("p%g: got interval low %s, want about 10ms", p.Quantile, time.Duration(p.Low))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeComparisonText(&buf, c); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), "latencies.50th") || !strings.Contains(buf.String(), "REGRESSION") {
+		t.Errorf("text comparison misses regressions:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeComparisonMarkdown(&buf, c); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), "| `success` | 1 | 0.9 | -0.1 | -10.00% | :warning: regression |") {
+		t.Errorf("markdown comparison misses regressions:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeComparisonJSON(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+
+	var got comparison
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	} else if got.Regressions != c.Regressions || len(got.Metrics) != len(c.Metrics) {
+		t.Errorf("got %d regressions of %d metrics, want %d of %d", got.Regressions, len(got.Metrics), c.Regressions, len(c.Metrics))
+	}
+}
@@ -15,6 +15,7 @@ const (
 	encodingCSV  = "csv"
 	encodingGob  = "gob"
 	encodingJSON = "json"
+	encodingHAR  = "har"
 )
 
 const encodeUsage = `Usage: vegeta encode [options] [<file>...]
@@ -22,7 +23,8 @@ const encodeUsage = `Usage: vegeta encode [options] [<file>...]
 Encodes vegeta attack results from one encoding to another.
 The supported encodings are Gob (binary), CSV and JSON.
 Each input file may have a different encoding which is detected
-automatically.
+automatically. Results can also be encoded as an HAR 1.2 document to be
+opened in browser developer tools, but not decoded from it.
 
 The CSV encoder doesn't write a header. The columns written by it are:
 
@@ -38,22 +40,27 @@ The CSV encoder doesn't write a header. The columns written by it are:
   10. Method
   11. URL
   12. Base64 encoded response headers
+  13. Local address
+  14. Response body hash
+  15. Trace ID
+  16. Span ID
 
 Arguments:
   <file>  A file with vegeta attack results encoded with one of
           the supported encodings (gob | json | csv) [default: stdin]
 
 Options:
-  --to      Output encoding (gob | json | csv) [default: json]
+  --to      Output encoding (gob | json | csv | har) [default: json]
   --output  Output file [default: stdout]
 
 Examples:
   echo "GET http://:80" | vegeta attack -rate=1/s > results.gob
   cat results.gob | vegeta encode | jq -c 'del(.body)' | vegeta encode -to gob
+  vegeta encode -to har results.gob > results.har
 `
 
 func encodeCmd() command {
-	encs := "[" + strings.Join([]string{encodingCSV, encodingGob, encodingJSON}, ", ") + "]"
+	encs := "[" + strings.Join([]string{encodingCSV, encodingGob, encodingJSON, encodingHAR}, ", ") + "]"
 	fs := flag.NewFlagSet("vegeta encode", flag.ExitOnError)
 	to := fs.String("to", encodingJSON, "Output encoding "+encs)
 	output := fs.String("output", "stdout", "Output file")
@@ -85,7 +92,11 @@ func encode(files []string, to, output string) error {
 	}
 	defer out.Close()
 
-	var enc vegeta.Encoder
+	var (
+		enc vegeta.Encoder
+		end = func() error { return nil }
+	)
+
 	switch to {
 	case encodingCSV:
 		enc = vegeta.NewCSVEncoder(out)
@@ -93,6 +104,8 @@ func encode(files []string, to, output string) error {
 		enc = vegeta.NewEncoder(out)
 	case encodingJSON:
 		enc = vegeta.NewJSONEncoder(out)
+	case encodingHAR:
+		enc, end = vegeta.NewHAREncoder(out)
 	default:
 		return fmt.Errorf("encode: unknown encoding %q", to)
 	}
@@ -100,10 +113,11 @@ func encode(files []string, to, output string) error {
 	sigch := make(chan os.Signal, 1)
 	signal.Notify(sigch, os.Interrupt)
 
+loop:
 	for {
 		select {
 		case <-sigch:
-			return nil
+			break loop
 		default:
 		}
 
@@ -118,5 +132,5 @@ func encode(files []string, to, output string) error {
 		}
 	}
 
-	return nil
+	return end()
 }
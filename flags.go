@@ -6,6 +6,8 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -46,12 +48,92 @@ func (h headers) Set(value string) error {
 	return nil
 }
 
-// localAddr implements the Flag interface for parsing net.IPAddr
-type localAddr struct{ *net.IPAddr }
+// localAddrs implements the flag.Value interface for a comma separated
+// list of local IP addresses, each given as an IP, a hostname or a CIDR range.
+type localAddrs []net.IPAddr
+
+// maxCIDRAddrs bounds the number of addresses a CIDR range may expand to.
+const maxCIDRAddrs = 1 << 16
+
+func (l *localAddrs) Set(value string) error {
+	var addrs []net.IPAddr
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+
+		if strings.Contains(v, "/") {
+			ips, err := cidrAddrs(v)
+			if err != nil {
+				return err
+			}
+			addrs = append(addrs, ips...)
+			continue
+		}
+
+		ip, err := net.ResolveIPAddr("ip", v)
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, *ip)
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("no local addresses in %q", value)
+	}
+
+	*l = addrs
+	return nil
+}
 
-func (ip *localAddr) Set(value string) (err error) {
-	ip.IPAddr, err = net.ResolveIPAddr("ip", value)
-	return
+func (l localAddrs) String() string {
+	addrs := make([]string, len(l))
+	for i := range l {
+		addrs[i] = l[i].String()
+	}
+	return strings.Join(addrs, ",")
+}
+
+// cidrAddrs returns all the addresses in the given CIDR range. The network
+// and broadcast addresses of IPv4 ranges are skipped since they can't be
+// bound to.
+func cidrAddrs(cidr string) ([]net.IPAddr, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if size := bits - ones; size >= 64 || uint64(1)<<size > maxCIDRAddrs {
+		return nil, fmt.Errorf("CIDR range %s has more than %d addresses", cidr, maxCIDRAddrs)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	var addrs []net.IPAddr
+	for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); ip = nextIP(ip) {
+		addrs = append(addrs, net.IPAddr{IP: ip})
+	}
+
+	if bits == 32 && bits-ones > 1 {
+		addrs = addrs[1 : len(addrs)-1]
+	}
+
+	return addrs, nil
+}
+
+// nextIP returns a copy of the given IP incremented by one.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i]++; next[i] != 0 {
+			break
+		}
+	}
+	return next
 }
 
 // csl implements the flag.Value interface for comma separated lists
@@ -155,6 +237,264 @@ func (f *dnsTTLFlag) String() string {
 	return f.ttl.String()
 }
 
+type tickQueueFlag struct{ n *int }
+
+func (f *tickQueueFlag) Set(v string) error {
+	switch {
+	case v == "block":
+		*(f.n) = vegeta.BlockTicks
+	case v == "drop":
+		*(f.n) = 0
+	case strings.HasPrefix(v, "queue:"):
+		n, err := strconv.Atoi(v[len("queue:"):])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid queue size in %q", v)
+		}
+		*(f.n) = n
+	default:
+		return fmt.Errorf("%q isn't one of [block, drop, queue:N]", v)
+	}
+	return nil
+}
+
+func (f *tickQueueFlag) String() string {
+	switch {
+	case f.n == nil || *(f.n) == vegeta.BlockTicks:
+		return "block"
+	case *(f.n) == 0:
+		return "drop"
+	default:
+		return "queue:" + strconv.Itoa(*(f.n))
+	}
+}
+
+// bodyCaptureFlag implements the flag.Value interface for the policy of
+// which response bodies are captured: all, or a comma separated list of
+// errors, sample:F and first:N.
+type bodyCaptureFlag struct{ c *vegeta.BodyCapture }
+
+func (f *bodyCaptureFlag) Set(v string) error {
+	var c vegeta.BodyCapture
+	if v == "all" {
+		*f.c = c
+		return nil
+	}
+
+	for _, p := range strings.Split(v, ",") {
+		switch p = strings.TrimSpace(p); {
+		case p == "errors":
+			c.Errors = true
+		case strings.HasPrefix(p, "sample:"):
+			r, err := strconv.ParseFloat(p[len("sample:"):], 64)
+			if err != nil || r <= 0 || r > 1 {
+				return fmt.Errorf("invalid sample fraction in %q", p)
+			}
+			c.Sample = r
+		case strings.HasPrefix(p, "first:"):
+			n, err := strconv.Atoi(p[len("first:"):])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid count in %q", p)
+			}
+			c.FirstPerCode = n
+		default:
+			return fmt.Errorf("%q isn't one of [all, errors, sample:F, first:N]", p)
+		}
+	}
+
+	*f.c = c
+	return nil
+}
+
+func (f *bodyCaptureFlag) String() string {
+	if f.c == nil {
+		return "all"
+	}
+
+	var ps []string
+	if f.c.Errors {
+		ps = append(ps, "errors")
+	}
+	if f.c.Sample > 0 {
+		ps = append(ps, "sample:"+strconv.FormatFloat(f.c.Sample, 'g', -1, 64))
+	}
+	if f.c.FirstPerCode > 0 {
+		ps = append(ps, "first:"+strconv.Itoa(f.c.FirstPerCode))
+	}
+	if len(ps) == 0 {
+		return "all"
+	}
+
+	return strings.Join(ps, ",")
+}
+
+// stopOnFlag implements the flag.Value interface for repeated stop conditions
+// of attacks: error-ratio:R[/N], consecutive-failures:N and pNN:D[/N].
+type stopOnFlag struct{ conds *[]vegeta.StopCondition }
+
+// Default windows of stop conditions, in number of results.
+const (
+	defaultErrorRatioWindow = 100
+	defaultLatencyMinSample = 100
+)
+
+func (f *stopOnFlag) Set(v string) error {
+	kind, arg, ok := strings.Cut(strings.TrimSpace(v), ":")
+	if !ok || arg == "" {
+		return fmt.Errorf("stop condition %q doesn't match the kind:value format", v)
+	}
+
+	value, window, hasWindow := strings.Cut(arg, "/")
+	n := 0
+	if hasWindow {
+		var err error
+		if n, err = strconv.Atoi(window); err != nil || n <= 0 {
+			return fmt.Errorf("invalid window in %q", v)
+		}
+	}
+
+	var c vegeta.StopCondition
+	switch {
+	case kind == "error-ratio":
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil || r < 0 || r >= 1 {
+			return fmt.Errorf("invalid error ratio in %q", v)
+		}
+		if !hasWindow {
+			n = defaultErrorRatioWindow
+		}
+		c = vegeta.ErrorRatio{Max: r, Window: n}
+	case kind == "consecutive-failures":
+		k, err := strconv.Atoi(value)
+		if err != nil || k <= 0 || hasWindow {
+			return fmt.Errorf("invalid number of failures in %q", v)
+		}
+		c = vegeta.ConsecutiveFailures{N: k}
+	case strings.HasPrefix(kind, "p"):
+		q, ok := parseQuantile(kind)
+		if !ok {
+			return fmt.Errorf("invalid quantile in %q", v)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid latency in %q", v)
+		}
+		if !hasWindow {
+			n = defaultLatencyMinSample
+		}
+		c = vegeta.LatencyCeiling{Quantile: q, Max: d, MinSamples: n}
+	default:
+		return fmt.Errorf("%q isn't one of [error-ratio:R[/N], consecutive-failures:N, pNN:D[/N]]", v)
+	}
+
+	*f.conds = append(*f.conds, c)
+	return nil
+}
+
+func (f *stopOnFlag) String() string {
+	if f.conds == nil {
+		return ""
+	}
+
+	cs := make([]string, 0, len(*f.conds))
+	for _, c := range *f.conds {
+		cs = append(cs, c.String())
+	}
+
+	return strings.Join(cs, ", ")
+}
+
+// parseQuantile parses a percentile given as pNN into a quantile,
+// e.g. p50 -> 0.5, p99 -> 0.99, p999 -> 0.999. The first two digits are
+// always the whole percent, so p5 and p100 are rejected as ambiguous.
+func parseQuantile(s string) (float64, bool) {
+	digits, ok := strings.CutPrefix(s, "p")
+	if !ok || len(digits) < 2 || strings.Trim(digits, "0123456789") != "" {
+		return 0, false
+	}
+
+	if len(digits) > 2 && digits[0] == '1' && strings.Trim(digits[1:], "0") == "" {
+		return 0, false
+	}
+
+	q, err := strconv.ParseFloat("0."+digits, 64)
+	return q, err == nil && q > 0
+}
+
+// weightsFlag implements the flag.Value interface for repeated weights
+// of operations given as name=N.
+type weightsFlag struct{ weights *map[string]int }
+
+func (f *weightsFlag) Set(v string) error {
+	i := strings.LastIndexByte(v, '=')
+	if i <= 0 {
+		return fmt.Errorf("weight %q doesn't match the name=N format", v)
+	}
+
+	n, err := strconv.Atoi(v[i+1:])
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid weight in %q", v)
+	}
+
+	if *f.weights == nil {
+		*f.weights = map[string]int{}
+	}
+
+	(*f.weights)[strings.TrimSpace(v[:i])] = n
+	return nil
+}
+
+func (f *weightsFlag) String() string {
+	if f.weights == nil {
+		return ""
+	}
+
+	ws := make([]string, 0, len(*f.weights))
+	for name, n := range *f.weights {
+		ws = append(ws, name+"="+strconv.Itoa(n))
+	}
+
+	sort.Strings(ws)
+	return strings.Join(ws, ",")
+}
+
+// accessLogFormatFlag implements the flag.Value interface for the format of
+// access logs, given by name or as a regular expression.
+type accessLogFormatFlag struct{ re **regexp.Regexp }
+
+func (f *accessLogFormatFlag) Set(v string) (err error) {
+	switch v {
+	case "combined":
+		*f.re = vegeta.CombinedLogFormat
+	case "common":
+		*f.re = vegeta.CommonLogFormat
+	default:
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return err
+		}
+
+		names := re.SubexpNames()
+		if !slices.Contains(names, "request") &&
+			(!slices.Contains(names, "method") || !slices.Contains(names, "path")) {
+			return fmt.Errorf("access log format %q needs a request group or method and path groups", v)
+		}
+
+		*f.re = re
+	}
+	return nil
+}
+
+func (f *accessLogFormatFlag) String() string {
+	switch {
+	case f.re == nil || *f.re == nil || *f.re == vegeta.CombinedLogFormat:
+		return "combined"
+	case *f.re == vegeta.CommonLogFormat:
+		return "common"
+	default:
+		return (*f.re).String()
+	}
+}
+
 const connectToFormat = "src:port:dst:port"
 
 type connectToFlag struct {
@@ -205,3 +545,35 @@ func (c *connectToFlag) Set(s string) error {
 
 	return nil
 }
+
+// urlRulesFlag implements the flag.Value interface for repeated URL
+// normalization rules given as <regexp>=><template>.
+type urlRulesFlag struct{ rules *[]vegeta.URLRule }
+
+func (f *urlRulesFlag) Set(v string) error {
+	pattern, template, ok := strings.Cut(v, "=>")
+	if !ok || pattern == "" {
+		return fmt.Errorf("URL rule %q doesn't match the <regexp>=><template> format", v)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regexp in URL rule %q: %w", v, err)
+	}
+
+	*f.rules = append(*f.rules, vegeta.URLRule{Pattern: re, Template: template})
+	return nil
+}
+
+func (f *urlRulesFlag) String() string {
+	if f.rules == nil {
+		return ""
+	}
+
+	rs := make([]string, 0, len(*f.rules))
+	for _, r := range *f.rules {
+		rs = append(rs, r.Pattern.String()+"=>"+r.Template)
+	}
+
+	return strings.Join(rs, ", ")
+}
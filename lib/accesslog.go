@@ -0,0 +1,308 @@
+package vegeta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLogTargetFormat is the human readable identifier for the access log
+// target format.
+const AccessLogTargetFormat = "access-log"
+
+var (
+	// CommonLogFormat matches the lines of access logs in the Common Log Format
+	// used by default by Apache and nginx.
+	CommonLogFormat = regexp.MustCompile(
+		`^(?P<host>\S+) \S+ \S+ \[(?P<time>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}|-) (?P<bytes>\d+|-)`)
+
+	// CombinedLogFormat matches the lines of access logs in the Combined Log
+	// Format, which extends the Common Log Format with the referer and user
+	// agent of requests. Lines in the Common Log Format match it as well.
+	CombinedLogFormat = regexp.MustCompile(CommonLogFormat.String() +
+		`(?: "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)")?`)
+)
+
+// accessLogTimeLayouts are the layouts tried to parse the time of log lines.
+var accessLogTimeLayouts = []string{"02/Jan/2006:15:04:05 -0700", time.RFC3339Nano}
+
+// AccessLogOptions define how the lines of an access log are read as Targets.
+type AccessLogOptions struct {
+	// BaseURL is the scheme and host, and optionally a path prefix, to which
+	// the logged request paths are sent. It's required.
+	BaseURL string
+	// Format matches each line of the log. Its named groups define the
+	// request of a line: either "request", as in "GET /path HTTP/1.1", or
+	// "method" and "path". Optional groups are "time", "referer" and
+	// "user_agent". It defaults to CombinedLogFormat.
+	Format *regexp.Regexp
+	// SkipMethods drops the requests with one of the given methods.
+	SkipMethods []string
+	// SkipPaths drops the requests with one of the given paths, without
+	// query. Paths ending in * drop all the paths with the preceding prefix.
+	SkipPaths []string
+}
+
+// NewAccessLogTargeter returns a new Targeter that decodes one Target from
+// the lines of the access log read from the given io.Reader on every
+// invocation, in the order they were logged. Lines whose request can't be
+// parsed, such as the ones of malformed requests, and requests dropped by the
+// given options are skipped.
+//
+// Each Target has the method and path of a logged request against the
+// options' base URL, and the Referer and User-Agent headers of the request
+// when logged.
+//
+// body will be set as each Target's body.
+// hdr will be merged with the each Target's headers.
+func NewAccessLogTargeter(src io.Reader, body []byte, hdr http.Header, opts AccessLogOptions) Targeter {
+	var (
+		mu sync.Mutex
+		sc = bufio.NewScanner(src)
+		p  = newAccessLogParser(opts)
+	)
+
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	return func(tgt *Target) error {
+		if tgt == nil {
+			return ErrNilTarget
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for sc.Scan() {
+			t, _, ok, err := p.parse(sc.Text())
+			if err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+
+			tgt.Method = t.Method
+			tgt.URL = t.URL
+			tgt.Body = body
+			tgt.Timeout, tgt.Redirects, tgt.MaxBody = nil, nil, nil
+			tgt.Multipart, tgt.BodyFile, tgt.BodyRandom = nil, "", 0
+
+			tgt.Header = http.Header{}
+			for k, vs := range hdr {
+				tgt.Header[k] = append(tgt.Header[k], vs...)
+			}
+
+			for k, vs := range t.Header {
+				tgt.Header[k] = append(tgt.Header[k], vs...)
+			}
+
+			return nil
+		}
+
+		if err := sc.Err(); err != nil {
+			return err
+		}
+
+		return ErrNoTargets
+	}
+}
+
+// ReadAccessLogTargets reads the access log from the given io.Reader and
+// returns the Targets of its lines as NewAccessLogTargeter does, along with
+// the times at which their requests were logged, both sorted by the latter.
+// The times can be used with NewReplayPacer to reproduce the timing of the
+// logged requests, and are required to be in every line.
+func ReadAccessLogTargets(src io.Reader, opts AccessLogOptions) ([]Target, []time.Time, error) {
+	var (
+		p       = newAccessLogParser(opts)
+		sc      = bufio.NewScanner(src)
+		tgts    []Target
+		started []time.Time
+	)
+
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for sc.Scan() {
+		t, ts, ok, err := p.parse(sc.Text())
+		if err != nil {
+			return nil, nil, err
+		} else if !ok {
+			continue
+		} else if ts.IsZero() {
+			return nil, nil, fmt.Errorf("bad access log line %d: no time", p.line)
+		}
+
+		tgts = append(tgts, t)
+		started = append(started, ts)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(tgts) == 0 {
+		return nil, nil, ErrNoTargets
+	}
+
+	sortByTime(tgts, started)
+	return tgts, started, nil
+}
+
+// sortByTime stably sorts the given targets along with the times at which
+// they were sent, so that the i-th target is still sent at the i-th time once
+// NewReplayPacer sorts the latter. Logs are written when requests finish, not
+// when they start, so their lines aren't always in order.
+func sortByTime(tgts []Target, started []time.Time) {
+	idx := make([]int, len(started))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		return started[idx[i]].Before(started[idx[j]])
+	})
+
+	ts := make([]Target, len(tgts))
+	times := make([]time.Time, len(started))
+	for i, j := range idx {
+		ts[i], times[i] = tgts[j], started[j]
+	}
+
+	copy(tgts, ts)
+	copy(started, times)
+}
+
+type accessLogParser struct {
+	AccessLogOptions
+	line int
+	err  error // Of bad options.
+}
+
+func newAccessLogParser(opts AccessLogOptions) *accessLogParser {
+	p := accessLogParser{AccessLogOptions: opts}
+	if p.Format == nil {
+		p.Format = CombinedLogFormat
+	}
+
+	if p.BaseURL == "" {
+		p.err = fmt.Errorf("access log base URL is required")
+	} else if u, err := url.Parse(p.BaseURL); err != nil || !u.IsAbs() {
+		p.err = fmt.Errorf("bad access log base URL: %s", p.BaseURL)
+	}
+
+	p.BaseURL = strings.TrimSuffix(p.BaseURL, "/")
+	return &p
+}
+
+// parse returns the Target and time of the given line, and whether it has a
+// request that isn't skipped.
+func (p *accessLogParser) parse(line string) (tgt Target, ts time.Time, ok bool, err error) {
+	if p.err != nil {
+		return tgt, ts, false, p.err
+	}
+
+	if p.line++; strings.TrimSpace(line) == "" {
+		return tgt, ts, false, nil
+	}
+
+	m := p.Format.FindStringSubmatch(line)
+	if m == nil {
+		return tgt, ts, false, fmt.Errorf("bad access log line %d: doesn't match format", p.line)
+	}
+
+	fields := map[string]string{}
+	for i, name := range p.Format.SubexpNames() {
+		if name != "" && m[i] != "" && m[i] != "-" {
+			fields[name] = m[i]
+		}
+	}
+
+	method, path := fields["method"], fields["path"]
+	if req, ok := fields["request"]; ok {
+		if parts := strings.Fields(req); len(parts) >= 2 {
+			method, path = parts[0], parts[1]
+		}
+	}
+
+	// Malformed requests, such as TLS handshakes to plain HTTP servers,
+	// are logged but can't be replayed.
+	if !validMethod(method) || !strings.HasPrefix(path, "/") {
+		return tgt, ts, false, nil
+	}
+
+	if p.skip(method, path) {
+		return tgt, ts, false, nil
+	}
+
+	tgt = Target{
+		Method: method,
+		URL:    p.BaseURL + path,
+		Header: http.Header{},
+	}
+
+	if ref, ok := fields["referer"]; ok {
+		tgt.Header["Referer"] = []string{ref}
+	}
+
+	if ua, ok := fields["user_agent"]; ok {
+		tgt.Header["User-Agent"] = []string{ua}
+	}
+
+	if v, ok := fields["time"]; ok {
+		for _, layout := range accessLogTimeLayouts {
+			if ts, err = time.Parse(layout, v); err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			return tgt, ts, false, fmt.Errorf("bad access log line %d time: %w", p.line, err)
+		}
+	}
+
+	return tgt, ts, true, nil
+}
+
+func (p *accessLogParser) skip(method, path string) bool {
+	for _, m := range p.SkipMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	for _, sp := range p.SkipPaths {
+		if prefix, ok := strings.CutSuffix(sp, "*"); ok && strings.HasPrefix(path, prefix) {
+			return true
+		} else if sp == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validMethod returns true if the given method is a valid HTTP token.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for _, c := range method {
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", c) &&
+			!('0' <= c && c <= '9') && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
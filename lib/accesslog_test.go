@@ -0,0 +1,145 @@
+package vegeta
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testAccessLog = `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /things?page=2 HTTP/1.1" 200 2326 "http://goku.dev/start" "Mozilla/4.08"
+127.0.0.1 - - [10/Oct/2000:13:55:37 -0700] "\x16\x03\x01" 400 0 "-" "-"
+
+10.0.0.1 - - [10/Oct/2000:13:55:38 -0700] "POST /things HTTP/1.1" 201 12
+10.0.0.1 - - [10/Oct/2000:13:55:39 -0700] "DELETE /admin/things/1 HTTP/1.1" 204 0 "-" "curl/8.0"
+`
+
+func TestReadAccessLogTargets(t *testing.T) {
+	t.Parallel()
+
+	began := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+
+	get := Target{
+		Method: "GET",
+		URL:    "http://localhost:8080/things?page=2",
+		Header: http.Header{"Referer": {"http://goku.dev/start"}, "User-Agent": {"Mozilla/4.08"}},
+	}
+	post := Target{Method: "POST", URL: "http://localhost:8080/things", Header: http.Header{}}
+	del := Target{Method: "DELETE", URL: "http://localhost:8080/admin/things/1", Header: http.Header{"User-Agent": {"curl/8.0"}}}
+
+	for _, tc := range []struct {
+		name    string
+		log     string
+		opts    AccessLogOptions
+		targets []Target
+		started []time.Time
+		err     string
+	}{
+		{
+			name:    "combined",
+			log:     testAccessLog,
+			opts:    AccessLogOptions{BaseURL: "http://localhost:8080/"},
+			targets: []Target{get, post, del},
+			started: []time.Time{began, began.Add(2 * time.Second), began.Add(3 * time.Second)},
+		},
+		{
+			name: "out of order",
+			log: `10.0.0.1 - - [10/Oct/2000:13:55:39 -0700] "DELETE /admin/things/1 HTTP/1.1" 204 0 "-" "curl/8.0"
+127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /things?page=2 HTTP/1.1" 200 2326 "http://goku.dev/start" "Mozilla/4.08"
+10.0.0.1 - - [10/Oct/2000:13:55:38 -0700] "POST /things HTTP/1.1" 201 12
+`,
+			opts:    AccessLogOptions{BaseURL: "http://localhost:8080"},
+			targets: []Target{get, post, del},
+			started: []time.Time{began, began.Add(2 * time.Second), began.Add(3 * time.Second)},
+		},
+		{
+			name: "skips",
+			log:  testAccessLog,
+			opts: AccessLogOptions{
+				BaseURL:     "http://localhost:8080",
+				SkipMethods: []string{"post"},
+				SkipPaths:   []string{"/admin/things/1"},
+			},
+			targets: []Target{get},
+			started: []time.Time{began},
+		},
+		{
+			name: "custom format",
+			log:  "2000-10-10T13:55:36-07:00 GET /things?page=2 200\n",
+			opts: AccessLogOptions{
+				BaseURL: "http://localhost:8080",
+				Format:  regexp.MustCompile(`^(?P<time>\S+) (?P<method>\S+) (?P<path>\S+)`),
+			},
+			targets: []Target{{Method: "GET", URL: "http://localhost:8080/things?page=2", Header: http.Header{}}},
+			started: []time.Time{began},
+		},
+		{
+			name: "no time",
+			log:  "GET /things\n",
+			opts: AccessLogOptions{BaseURL: "http://localhost:8080", Format: regexp.MustCompile(`^(?P<request>.+)$`)},
+			err:  "bad access log line 1: no time",
+		},
+		{
+			name: "no match",
+			log:  "garbage\n",
+			opts: AccessLogOptions{BaseURL: "http://localhost:8080"},
+			err:  "bad access log line 1: doesn't match format",
+		},
+		{
+			name: "no base URL",
+			log:  testAccessLog,
+			err:  "base URL is required",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			targets, started, err := ReadAccessLogTargets(strings.NewReader(tc.log), tc.opts)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.targets, targets); diff != "" {
+				t.Errorf("unexpected targets (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.started, started); diff != "" {
+				t.Errorf("unexpected start times (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccessLogTargeter(t *testing.T) {
+	t.Parallel()
+
+	hdr := http.Header{"Accept": {"*/*"}}
+	tr := NewAccessLogTargeter(strings.NewReader(testAccessLog), []byte("body"), hdr, AccessLogOptions{
+		BaseURL:   "https://goku.dev/api",
+		SkipPaths: []string{"/admin/*"},
+		Format:    CommonLogFormat,
+	})
+
+	want := []Target{
+		{Method: "GET", URL: "https://goku.dev/api/things?page=2", Body: []byte("body"), Header: http.Header{"Accept": {"*/*"}}},
+		{Method: "POST", URL: "https://goku.dev/api/things", Body: []byte("body"), Header: http.Header{"Accept": {"*/*"}}},
+	}
+
+	got, err := ReadAllTargets(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected targets (-want +got):\n%s", diff)
+	}
+}
@@ -3,17 +3,24 @@ package vegeta
 import (
 	"context"
 	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/rs/dnscache"
 	"golang.org/x/net/http2"
 )
@@ -21,17 +28,22 @@ import (
 // Attacker is an attack executor which wraps an http.Client
 type Attacker struct {
 	dialer     *net.Dialer
+	laddrs     *localAddrs
 	client     http.Client
-	stopch     chan struct{}
-	stopOnce   sync.Once
+	mu         sync.Mutex
+	attacks    map[*attack]struct{}
 	workers    uint64
 	maxWorkers uint64
+	tickQueue  int
 	maxBody    int64
+	capture    BodyCapture
 	redirects  int
-	seqmu      sync.Mutex
-	seq        uint64
-	began      time.Time
 	chunked    bool
+	stops      []StopCondition
+	tripped    map[<-chan *Result]StopCondition // By the Results of the attacks.
+	done       map[string]AttackStats           // Totals of the finished attacks by name.
+
+	traceContext bool
 }
 
 const (
@@ -56,8 +68,15 @@ const (
 	DefaultMaxBody = int64(-1)
 	// NoFollow is the value when redirects are not followed but marked successful
 	NoFollow = -1
+	// BlockTicks is the TickQueue value with which an Attacker blocks its
+	// Pacer until a worker is free, instead of dropping ticks.
+	BlockTicks = -1
 )
 
+// ErrTickDropped is the error of the Results an Attacker sends for the ticks
+// of its Pacer it dropped because all workers were busy. See TickQueue.
+var ErrTickDropped = errors.New("tick dropped: all workers are busy")
+
 var (
 	// DefaultLocalAddr is the default local IP address an Attacker uses.
 	DefaultLocalAddr = net.IPAddr{IP: net.IPv4zero}
@@ -69,10 +88,12 @@ var (
 // by the optionally provided opts.
 func NewAttacker(opts ...func(*Attacker)) *Attacker {
 	a := &Attacker{
-		stopch:     make(chan struct{}),
-		stopOnce:   sync.Once{},
+		attacks:    map[*attack]struct{}{},
+		tripped:    map[<-chan *Result]StopCondition{},
+		done:       map[string]AttackStats{},
 		workers:    DefaultWorkers,
 		maxWorkers: DefaultMaxWorkers,
+		tickQueue:  BlockTicks,
 		maxBody:    DefaultMaxBody,
 	}
 
@@ -85,7 +106,7 @@ func NewAttacker(opts ...func(*Attacker)) *Attacker {
 		Timeout: DefaultTimeout,
 		Transport: &http.Transport{
 			Proxy:               http.ProxyFromEnvironment,
-			DialContext:         a.dialer.DialContext,
+			DialContext:         a.dialContext,
 			TLSClientConfig:     DefaultTLSConfig,
 			MaxIdleConnsPerHost: DefaultConnections,
 			MaxConnsPerHost:     DefaultMaxConnections,
@@ -112,6 +133,17 @@ func MaxWorkers(n uint64) func(*Attacker) {
 	return func(a *Attacker) { a.maxWorkers = n }
 }
 
+// TickQueue returns a functional option which sets how an Attacker handles the
+// ticks of its Pacer once MaxWorkers is reached and all workers are busy.
+// With BlockTicks, the default, the Pacer blocks until a worker is free, which
+// makes the achieved rate fall below the requested one. Otherwise, up to n
+// ticks are queued waiting for a free worker and any further ones are dropped
+// and sent as Results with the ErrTickDropped error, so that reports show the
+// hits the Attacker couldn't send.
+func TickQueue(n int) func(*Attacker) {
+	return func(a *Attacker) { a.tickQueue = n }
+}
+
 // Connections returns a functional option which sets the number of maximum idle
 // open connections per target host.
 func Connections(n int) func(*Attacker) {
@@ -141,15 +173,21 @@ func ChunkedBody(b bool) func(*Attacker) {
 func Redirects(n int) func(*Attacker) {
 	return func(a *Attacker) {
 		a.redirects = n
-		a.client.CheckRedirect = func(_ *http.Request, via []*http.Request) error {
-			switch {
-			case n == NoFollow:
-				return http.ErrUseLastResponse
-			case n < len(via):
-				return fmt.Errorf("stopped after %d redirects", n)
-			default:
-				return nil
-			}
+		a.client.CheckRedirect = checkRedirect(n)
+	}
+}
+
+// checkRedirect returns an http.Client CheckRedirect function that follows
+// up to n redirects.
+func checkRedirect(n int) func(*http.Request, []*http.Request) error {
+	return func(_ *http.Request, via []*http.Request) error {
+		switch {
+		case n == NoFollow:
+			return http.ErrUseLastResponse
+		case n < len(via):
+			return fmt.Errorf("stopped after %d redirects", n)
+		default:
+			return nil
 		}
 	}
 }
@@ -177,8 +215,57 @@ func LocalAddr(addr net.IPAddr) func(*Attacker) {
 	return func(a *Attacker) {
 		tr := a.client.Transport.(*http.Transport)
 		a.dialer.LocalAddr = &net.TCPAddr{IP: addr.IP, Zone: addr.Zone}
-		tr.DialContext = a.dialer.DialContext
+		a.laddrs = nil
+		tr.DialContext = a.dialContext
+	}
+}
+
+// LocalAddrs returns a functional option which makes an Attacker rotate
+// across the given local addresses when dialing new connections, either in
+// round-robin order or at random. Since connections are reused when
+// KeepAlive is enabled, rotation happens per connection, not per request.
+func LocalAddrs(addrs []net.IPAddr, random bool) func(*Attacker) {
+	return func(a *Attacker) {
+		if len(addrs) == 1 {
+			LocalAddr(addrs[0])(a)
+			return
+		} else if len(addrs) == 0 {
+			return
+		}
+
+		tr := a.client.Transport.(*http.Transport)
+		a.laddrs = &localAddrs{addrs: addrs, random: random}
+		tr.DialContext = a.dialContext
+	}
+}
+
+// localAddrs rotates over a set of local addresses.
+type localAddrs struct {
+	addrs  []net.IPAddr
+	random bool
+	n      uint64
+}
+
+func (l *localAddrs) next() *net.TCPAddr {
+	var i uint64
+	if l.random {
+		i = rand.Uint64()
+	} else {
+		i = atomic.AddUint64(&l.n, 1) - 1
 	}
+	addr := l.addrs[i%uint64(len(l.addrs))]
+	return &net.TCPAddr{IP: addr.IP, Zone: addr.Zone}
+}
+
+// dialContext dials with the Attacker's dialer, picking the next local
+// address to dial from when rotating across many.
+func (a *Attacker) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if a.laddrs == nil {
+		return a.dialer.DialContext(ctx, network, addr)
+	}
+	d := *a.dialer
+	d.LocalAddr = a.laddrs.next()
+	return d.DialContext(ctx, network, addr)
 }
 
 // KeepAlive returns a functional option which toggles KeepAlive
@@ -189,7 +276,7 @@ func KeepAlive(keepalive bool) func(*Attacker) {
 		tr.DisableKeepAlives = !keepalive
 		if !keepalive {
 			a.dialer.KeepAlive = 0
-			tr.DialContext = a.dialer.DialContext
+			tr.DialContext = a.dialContext
 		}
 	}
 }
@@ -232,11 +319,49 @@ func H2C(enabled bool) func(*Attacker) {
 }
 
 // MaxBody returns a functional option which limits the max number of bytes
-// read from response bodies. Set to -1 to disable any limits.
+// of response bodies captured in Results. Set to -1 to disable any limits.
+// Bodies are still read in full, so that the BytesIn and BodyHash of Results
+// are those of the whole body.
 func MaxBody(n int64) func(*Attacker) {
 	return func(a *Attacker) { a.maxBody = n }
 }
 
+// BodyCapture is a policy of which response bodies an Attacker captures in
+// Results, up to its MaxBody. A body is captured when it matches any of the
+// set criteria, and all bodies are captured when none is set. The length and
+// hash of every response body are recorded in Results regardless.
+type BodyCapture struct {
+	// Errors captures the bodies of responses with errors.
+	Errors bool
+	// Sample captures the bodies of the given fraction of responses,
+	// randomly sampled, between 0 and 1.
+	Sample float64
+	// FirstPerCode captures the bodies of the first given number of responses
+	// with each status code in an attack, counting those captured by the
+	// other criteria too.
+	FirstPerCode int
+}
+
+// captureAll returns true if the policy captures all bodies.
+func (c BodyCapture) captureAll() bool {
+	return !c.Errors && c.Sample <= 0 && c.FirstPerCode <= 0
+}
+
+// CaptureBodies returns a functional option which sets the policy of which
+// response bodies are captured in Results. By default, all are captured.
+func CaptureBodies(c BodyCapture) func(*Attacker) {
+	return func(a *Attacker) { a.capture = c }
+}
+
+// StopOn returns a functional option which sets the conditions on the Results
+// of each attack which, once met, stop that attack early, leaving any other
+// attacks of the Attacker running. The condition that was met is then
+// returned by Tripped. The Results of ticks dropped per TickQueue aren't
+// checked.
+func StopOn(conds ...StopCondition) func(*Attacker) {
+	return func(a *Attacker) { a.stops = conds }
+}
+
 // UnixSocket changes the dialer for the attacker to use the specified unix socket file
 func UnixSocket(socket string) func(*Attacker) {
 	return func(a *Attacker) {
@@ -291,7 +416,7 @@ func ConnectTo(addrMap map[string][]string) func(*Attacker) {
 
 		dial := tr.DialContext
 		if dial == nil {
-			dial = a.dialer.DialContext
+			dial = a.dialContext
 		}
 
 		type roundRobin struct {
@@ -316,9 +441,8 @@ func ConnectTo(addrMap map[string][]string) func(*Attacker) {
 
 // DNSCaching returns a functional option that enables DNS caching for
 // the given ttl. When ttl is zero cached entries will never expire.
-// When ttl is non-zero, this will start a refresh go-routine that updates
-// the cache every ttl interval. This go-routine will be stopped when the
-// attack is stopped.
+// When ttl is non-zero, the cache is refreshed in the background by the
+// first dial that happens at least ttl after the previous refresh.
 // When the ttl is negative, no caching will be performed.
 func DNSCaching(ttl time.Duration) func(*Attacker) {
 	return func(a *Attacker) {
@@ -329,25 +453,13 @@ func DNSCaching(ttl time.Duration) func(*Attacker) {
 		if tr, ok := a.client.Transport.(*http.Transport); ok {
 			dial := tr.DialContext
 			if dial == nil {
-				dial = a.dialer.DialContext
+				dial = a.dialContext
 			}
 
 			resolver := &dnscache.Resolver{}
 
-			if ttl != 0 {
-				go func() {
-					refresh := time.NewTicker(ttl)
-					defer refresh.Stop()
-					for {
-						select {
-						case <-refresh.C:
-							resolver.Refresh(true)
-						case <-a.stopch:
-							return
-						}
-					}
-				}()
-			}
+			var refreshed atomic.Int64
+			refreshed.Store(time.Now().UnixNano())
 
 			rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -357,6 +469,13 @@ func DNSCaching(ttl time.Duration) func(*Attacker) {
 					return nil, err
 				}
 
+				if ttl != 0 {
+					now, last := time.Now().UnixNano(), refreshed.Load()
+					if time.Duration(now-last) >= ttl && refreshed.CompareAndSwap(last, now) {
+						go resolver.Refresh(true)
+					}
+				}
+
 				ips, err := resolver.LookupHost(ctx, host)
 				if err != nil {
 					return nil, err
@@ -434,9 +553,150 @@ func firstOfEachIPFamily(ips []string) []string {
 type attack struct {
 	name  string
 	began time.Time
+	ctx   context.Context
+
+	stopch   chan struct{}
+	stopOnce sync.Once
 
 	seqmu sync.Mutex
 	seq   uint64
+
+	codesmu sync.Mutex
+	codes   map[uint16]int // Responses per status code.
+
+	checksmu sync.Mutex
+	checks   []func(*Result) bool // Of the Attacker's stop conditions.
+	tripped  StopCondition        // The first one that was met.
+
+	// Internal state exposed in AttackStats.
+	pacer    Pacer
+	workers  atomic.Uint64
+	inflight atomic.Uint64
+	hits     atomic.Uint64
+	due      atomic.Int64 // When the pending hit is due since began, or -1.
+	lag      atomic.Int64 // Of the last hit.
+}
+
+// AttackStats is a snapshot of the internal state of an attack run by an
+// Attacker, which tells whether the Attacker itself is saturated, such as
+// when it can't keep up with its Pacer because all of its workers are busy.
+type AttackStats struct {
+	// Name is the name of the attack.
+	Name string
+	// Began is when the attack began.
+	Began time.Time
+	// Elapsed is the duration of the attack so far.
+	Elapsed time.Duration
+	// Workers is the number of workers of the attack.
+	Workers uint64
+	// InFlight is the number of hits whose request is being sent or whose
+	// response is being read.
+	InFlight uint64
+	// Hits is the number of hits sent to the workers or dropped so far.
+	Hits uint64
+	// TargetRate is the hit rate per second of the Pacer at Elapsed.
+	TargetRate float64
+	// Lag is how long the pending hit has been overdue, such as while waiting
+	// for a free worker, or if there's none, how late the last hit was.
+	Lag time.Duration
+	// Done is whether the stats are of the finished attacks with Name, whose
+	// Hits are added up, while Began, Elapsed and Lag are of the last one to
+	// finish. They have no workers, hits in flight nor target rate.
+	Done bool
+}
+
+// Rate returns the mean hit rate per second achieved so far.
+func (s AttackStats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Hits) / s.Elapsed.Seconds()
+}
+
+// stats returns the AttackStats of the attack at the current time.
+func (atk *attack) stats() AttackStats {
+	elapsed := time.Since(atk.began)
+
+	lag := time.Duration(atk.lag.Load())
+	if due := atk.due.Load(); due >= 0 {
+		lag = max(elapsed-time.Duration(due), 0)
+	}
+
+	return AttackStats{
+		Name:       atk.name,
+		Began:      atk.began,
+		Elapsed:    elapsed,
+		Workers:    atk.workers.Load(),
+		InFlight:   atk.inflight.Load(),
+		Hits:       atk.hits.Load(),
+		TargetRate: atk.pacer.Rate(elapsed),
+		Lag:        lag,
+	}
+}
+
+// paced records that the pending hit, due at the given duration since the
+// attack began, was sent to a worker or dropped.
+func (atk *attack) paced(hits uint64, due time.Duration) {
+	atk.hits.Store(hits)
+	atk.lag.Store(int64(max(time.Since(atk.began)-due, 0)))
+	atk.due.Store(-1)
+}
+
+// capture returns true if the body of a response with the given status code
+// is captured according to the given policy. Every response is counted
+// towards FirstPerCode, whichever criteria its body is captured by.
+func (atk *attack) capture(c BodyCapture, code uint16, failed bool) bool {
+	first := false
+	if c.FirstPerCode > 0 {
+		atk.codesmu.Lock()
+		if atk.codes == nil {
+			atk.codes = map[uint16]int{}
+		}
+		atk.codes[code]++
+		first = atk.codes[code] <= c.FirstPerCode
+		atk.codesmu.Unlock()
+	}
+
+	switch {
+	case c.captureAll(), first:
+		return true
+	case c.Errors && failed:
+		return true
+	case c.Sample > 0 && rand.Float64() < c.Sample:
+		return true
+	}
+	return false
+}
+
+// stop signals the attack to stop. It returns false if it had already
+// been signalled to stop before.
+func (atk *attack) stop() (stopped bool) {
+	atk.stopOnce.Do(func() {
+		stopped = true
+		if atk.stopch != nil {
+			close(atk.stopch)
+		}
+	})
+	return stopped
+}
+
+// stamp sets the sequence number and timestamp of the given Result.
+func (atk *attack) stamp(res *Result) {
+	//
+	// Subtleness ahead! We need to compute the result timestamp in
+	// the same critical section that protects the increment of the sequence
+	// number because we want the same total ordering of timestamps and sequence
+	// numbers. That is, we wouldn't want two results A and B where A.seq > B.seq
+	// but A.timestamp < B.timestamp.
+	//
+	// Additionally, we calculate the result timestamp based on the same beginning
+	// timestamp using the Add method, which will use monotonic time calculations.
+	//
+	atk.seqmu.Lock()
+	res.Timestamp = atk.began.Add(time.Since(atk.began))
+	res.Seq = atk.seq
+	atk.seq++
+	atk.seqmu.Unlock()
 }
 
 // Attack reads its Targets from the passed Targeter and attacks them at
@@ -444,6 +704,14 @@ type attack struct {
 // runs until Stop is called. Results are sent to the returned channel as soon
 // as they arrive and will have their Attack field set to the given name.
 func (a *Attacker) Attack(tr Targeter, p Pacer, du time.Duration, name string) <-chan *Result {
+	return a.AttackContext(context.Background(), tr, p, du, name)
+}
+
+// AttackContext is like Attack, but the attack also stops when the given
+// context is done, in which case in-flight requests are cancelled too.
+// Cancelling the context only affects this attack, so an Attacker can run
+// many attacks concurrently, each with its own context.
+func (a *Attacker) AttackContext(ctx context.Context, tr Targeter, p Pacer, du time.Duration, name string) <-chan *Result {
 	var wg sync.WaitGroup
 
 	workers := a.workers
@@ -452,12 +720,28 @@ func (a *Attacker) Attack(tr Targeter, p Pacer, du time.Duration, name string) <
 	}
 
 	atk := &attack{
-		name:  name,
-		began: time.Now(),
+		name:   name,
+		began:  time.Now(),
+		ctx:    ctx,
+		stopch: make(chan struct{}),
+		pacer:  p,
 	}
 
+	atk.workers.Store(workers)
+	atk.due.Store(-1)
+
+	for _, c := range a.stops {
+		atk.checks = append(atk.checks, c.Check())
+	}
+
+	a.mu.Lock()
+	a.attacks[atk] = struct{}{}
+	a.mu.Unlock()
+
+	unwatch := context.AfterFunc(ctx, func() { atk.stop() })
+
 	results := make(chan *Result)
-	ticks := make(chan struct{})
+	ticks := make(chan struct{}, max(a.tickQueue, 0))
 	for i := uint64(0); i < workers; i++ {
 		wg.Add(1)
 		go a.attack(tr, atk, &wg, ticks, results)
@@ -465,10 +749,31 @@ func (a *Attacker) Attack(tr Targeter, p Pacer, du time.Duration, name string) <
 
 	go func() {
 		defer func() {
+			atk.due.Store(-1) // No more hits are due.
 			close(ticks)
 			wg.Wait()
+
+			last := atk.stats()
+
+			a.mu.Lock()
+			if atk.tripped != nil {
+				a.tripped[results] = atk.tripped
+			}
+			delete(a.attacks, atk)
+			done := a.done[atk.name]
+			a.done[atk.name] = AttackStats{
+				Name:    atk.name,
+				Began:   last.Began,
+				Elapsed: last.Elapsed,
+				Hits:    done.Hits + last.Hits,
+				Lag:     last.Lag,
+				Done:    true,
+			}
+			a.mu.Unlock()
+
 			close(results)
-			a.Stop()
+			unwatch()
+			atk.stop()
 		}()
 
 		count := uint64(0)
@@ -483,27 +788,69 @@ func (a *Attacker) Attack(tr Targeter, p Pacer, du time.Duration, name string) <
 				return
 			}
 
-			time.Sleep(wait)
+			due := elapsed + max(wait, 0)
+			atk.due.Store(int64(due))
+
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-atk.stopch:
+					timer.Stop()
+					return
+				}
+			}
 
 			if workers < a.maxWorkers {
 				select {
 				case ticks <- struct{}{}:
 					count++
+					atk.paced(count, due)
+					if len(ticks) == 0 {
+						continue
+					}
+					// the tick got queued, so all workers are blocked. start one more
+					workers++
+					atk.workers.Store(workers)
+					wg.Add(1)
+					go a.attack(tr, atk, &wg, ticks, results)
 					continue
-				case <-a.stopch:
+				case <-atk.stopch:
 					return
 				default:
 					// all workers are blocked. start one more and try again
 					workers++
+					atk.workers.Store(workers)
 					wg.Add(1)
 					go a.attack(tr, atk, &wg, ticks, results)
 				}
+			} else if a.tickQueue != BlockTicks {
+				select {
+				case ticks <- struct{}{}:
+				case <-atk.stopch:
+					return
+				default:
+					// all workers are blocked and the queue is full. drop the tick
+					// Dropped ticks aren't checked against the stop conditions,
+					// since they're due to the Attacker's saturation rather
+					// than to the targets.
+					res := a.drop(atk)
+					select {
+					case results <- res:
+					case <-atk.stopch:
+						return
+					}
+				}
+				count++
+				atk.paced(count, due)
+				continue
 			}
 
 			select {
 			case ticks <- struct{}{}:
 				count++
-			case <-a.stopch:
+				atk.paced(count, due)
+			case <-atk.stopch:
 				return
 			}
 		}
@@ -512,25 +859,97 @@ func (a *Attacker) Attack(tr Targeter, p Pacer, du time.Duration, name string) <
 	return results
 }
 
-// Stop stops the current attack. The return value indicates whether this call
-// has signalled the attack to stop (`true` for the first call) or whether it
-// was a noop because it has been previously signalled to stop (`false` for any
-// subsequent calls).
+// Stop stops all the attacks currently running. In-flight requests are left
+// to complete. The return value indicates whether this call has signalled any
+// attack to stop (`true` for the first call) or whether it was a noop because
+// they all have been previously signalled to stop or none was running (`false`).
+// Attacks started after Stop returns aren't affected by it.
 func (a *Attacker) Stop() bool {
-	select {
-	case <-a.stopch:
-		return false
-	default:
-		a.stopOnce.Do(func() { close(a.stopch) })
-		return true
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stopped := false
+	for atk := range a.attacks {
+		if atk.stop() {
+			stopped = true
+		}
 	}
+
+	return stopped
 }
 
 func (a *Attacker) attack(tr Targeter, atk *attack, workers *sync.WaitGroup, ticks <-chan struct{}, results chan<- *Result) {
 	defer workers.Done()
 	for range ticks {
-		results <- a.hit(tr, atk)
+		atk.inflight.Add(1)
+		res := a.hit(tr, atk)
+		atk.inflight.Add(^uint64(0))
+		a.check(atk, res)
+		results <- res
+	}
+}
+
+// check checks the given Result against the stop conditions of the attack,
+// and stops the attack once one is met.
+func (a *Attacker) check(atk *attack, res *Result) {
+	if len(atk.checks) == 0 {
+		return
 	}
+
+	atk.checksmu.Lock()
+	defer atk.checksmu.Unlock()
+
+	if atk.tripped != nil {
+		return
+	}
+
+	for i, check := range atk.checks {
+		if check(res) {
+			atk.tripped = a.stops[i]
+			atk.stop()
+			return
+		}
+	}
+}
+
+// Tripped returns the StopCondition that was met and stopped the attack whose
+// Results are sent to the given channel, as returned by Attack, or nil if
+// none was. It's known by the time the channel is closed, and forgotten once
+// returned, so that reused Attackers don't retain it, which makes following
+// calls return nil.
+func (a *Attacker) Tripped(results <-chan *Result) StopCondition {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c := a.tripped[results]
+	delete(a.tripped, results)
+	return c
+}
+
+// Stats returns the AttackStats of the attacks currently running, as well as
+// the ones adding up the finished attacks of each name, in the order they
+// began.
+func (a *Attacker) Stats() []AttackStats {
+	a.mu.Lock()
+	stats := make([]AttackStats, 0, len(a.done)+len(a.attacks))
+	for _, s := range a.done {
+		stats = append(stats, s)
+	}
+	for atk := range a.attacks {
+		stats = append(stats, atk.stats())
+	}
+	a.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Began.Before(stats[j].Began) })
+	return stats
+}
+
+// drop returns the Result of a tick that was dropped because all workers
+// were busy.
+func (a *Attacker) drop(atk *attack) *Result {
+	res := Result{Attack: atk.name, Error: ErrTickDropped.Error()}
+	atk.stamp(&res)
+	return &res
 }
 
 func (a *Attacker) hit(tr Targeter, atk *attack) *Result {
@@ -540,21 +959,7 @@ func (a *Attacker) hit(tr Targeter, atk *attack) *Result {
 		err error
 	)
 
-	//
-	// Subtleness ahead! We need to compute the result timestamp in
-	// the same critical section that protects the increment of the sequence
-	// number because we want the same total ordering of timestamps and sequence
-	// numbers. That is, we wouldn't want two results A and B where A.seq > B.seq
-	// but A.timestamp < B.timestamp.
-	//
-	// Additionally, we calculate the result timestamp based on the same beginning
-	// timestamp using the Add method, which will use monotonic time calculations.
-	//
-	atk.seqmu.Lock()
-	res.Timestamp = atk.began.Add(time.Since(atk.began))
-	res.Seq = atk.seq
-	atk.seq++
-	atk.seqmu.Unlock()
+	atk.stamp(&res)
 
 	defer func() {
 		res.Latency = time.Since(res.Timestamp)
@@ -564,7 +969,7 @@ func (a *Attacker) hit(tr Targeter, atk *attack) *Result {
 	}()
 
 	if err = tr(&tgt); err != nil {
-		a.Stop()
+		atk.stop()
 		return &res
 	}
 
@@ -582,34 +987,88 @@ func (a *Attacker) hit(tr Targeter, atk *attack) *Result {
 
 	req.Header.Set("X-Vegeta-Seq", strconv.FormatUint(res.Seq, 10))
 
+	if a.traceContext {
+		res.TraceID, res.SpanID = newTraceID(), newSpanID()
+		for k := range req.Header {
+			// Targets' headers aren't always canonical, such as of JSON targets.
+			if strings.EqualFold(k, "Traceparent") {
+				delete(req.Header, k)
+			}
+		}
+		req.Header.Set("Traceparent", traceParent(&res))
+	}
+
+	ctx := req.Context()
+	if atk.ctx != nil {
+		ctx = atk.ctx
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			res.LocalAddr = localIP(info.Conn.LocalAddr())
+		},
+	}))
+
 	if a.chunked {
 		req.TransferEncoding = append(req.TransferEncoding, "chunked")
 	}
 
-	r, err := a.client.Do(req)
+	// Targets can override the Attacker's timeout and redirects policy,
+	// in which case a shallow copy of the client is used for the request.
+	client := &a.client
+	if tgt.Timeout != nil || tgt.Redirects != nil {
+		c := a.client
+		if tgt.Timeout != nil {
+			c.Timeout = *tgt.Timeout
+		}
+		if tgt.Redirects != nil {
+			c.CheckRedirect = checkRedirect(*tgt.Redirects)
+		}
+		client = &c
+	}
+
+	r, err := client.Do(req)
 	if err != nil {
 		return &res
 	}
 	defer r.Body.Close()
 
-	body := io.Reader(r.Body)
-	if a.maxBody >= 0 {
-		body = io.LimitReader(r.Body, a.maxBody)
+	maxBody := a.maxBody
+	if tgt.MaxBody != nil {
+		maxBody = *tgt.MaxBody
 	}
 
-	if res.Body, err = io.ReadAll(body); err != nil {
-		return &res
-	} else if _, err = io.Copy(io.Discard, r.Body); err != nil {
+	res.Code = uint16(r.StatusCode)
+	failed := res.Code < 200 || res.Code >= 400
+
+	// The whole body is hashed and counted, but only captured up to
+	// maxBody when the capture policy says so.
+	hash := xxhash.New()
+	body := io.TeeReader(r.Body, hash)
+	if atk.capture(a.capture, res.Code, failed) {
+		captured := body
+		if maxBody >= 0 {
+			captured = io.LimitReader(body, maxBody)
+		}
+
+		if res.Body, err = io.ReadAll(captured); err != nil {
+			return &res
+		}
+	}
+
+	rest, err := io.Copy(io.Discard, body)
+	if err != nil {
 		return &res
 	}
 
-	res.BytesIn = uint64(len(res.Body))
+	res.BytesIn = uint64(len(res.Body)) + uint64(rest)
+	res.BodyHash = hex.EncodeToString(hash.Sum(nil))
 
 	if req.ContentLength != -1 {
 		res.BytesOut = uint64(req.ContentLength)
 	}
 
-	if res.Code = uint16(r.StatusCode); res.Code < 200 || res.Code >= 400 {
+	if failed {
 		res.Error = r.Status
 	}
 
@@ -617,3 +1076,14 @@ func (a *Attacker) hit(tr Targeter, atk *attack) *Result {
 
 	return &res
 }
+
+// localIP returns the IP of the given local address of a connection, or its
+// string representation when it has no IP, such as with unix sockets.
+func localIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	} else if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	return addr.String()
+}
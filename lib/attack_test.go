@@ -2,7 +2,10 @@ package vegeta
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,12 +16,14 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -64,6 +69,133 @@ func TestAttackDuration(t *testing.T) {
 	}
 }
 
+func TestAttackContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	rate := Rate{Freq: 100, Per: time.Second}
+	atk := NewAttacker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := atk.AttackContext(ctx, tr, rate, 0, "cancelled")
+	stopped := atk.Attack(tr, rate, 0, "stopped")
+
+	time.AfterFunc(200*time.Millisecond, cancel)
+	for range cancelled {
+	}
+
+	select {
+	case _, ok := <-stopped:
+		if !ok {
+			t.Fatal("cancelling one attack's context stopped the other")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("attack stopped sending results")
+	}
+
+	if !atk.Stop() {
+		t.Fatal("Stop didn't signal the running attack")
+	}
+	for range stopped {
+	}
+
+	if atk.Stop() {
+		t.Error("Stop signalled attacks with none running")
+	}
+
+	// A stopped Attacker can be reused.
+	var hits uint64
+	for range atk.Attack(tr, rate, 200*time.Millisecond, "reused") {
+		hits++
+	}
+
+	if got, want := hits, uint64(20); got != want {
+		t.Errorf("got %d hits after Stop, want %d", got, want)
+	}
+}
+
+func TestTickQueue(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	rate := Rate{Freq: 100, Per: time.Second}
+
+	for _, tc := range []struct {
+		queue   int
+		dropped bool
+	}{
+		{queue: BlockTicks, dropped: false},
+		{queue: 0, dropped: true},
+		{queue: 5, dropped: true},
+	} {
+		t.Run(strconv.Itoa(tc.queue), func(t *testing.T) {
+			atk := NewAttacker(Workers(1), MaxWorkers(1), TickQueue(tc.queue))
+
+			var hits, dropped int
+			var seqs []uint64
+			for res := range atk.Attack(tr, rate, 500*time.Millisecond, "") {
+				if res.Error == ErrTickDropped.Error() {
+					dropped++
+				} else {
+					hits++
+				}
+				seqs = append(seqs, res.Seq)
+			}
+
+			if got := dropped > 0; got != tc.dropped {
+				t.Fatalf("got %d dropped ticks, want dropped: %t", dropped, tc.dropped)
+			}
+
+			if hits > 10 {
+				t.Errorf("got %d hits, want at most %d", hits, 10)
+			}
+
+			if tc.dropped && hits+dropped < rate.Freq/2-5 {
+				t.Errorf("got %d results, want about %d", hits+dropped, rate.Freq/2)
+			}
+
+			sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+			for i, seq := range seqs {
+				if seq != uint64(i) {
+					t.Fatalf("non contiguous sequence numbers: %v", seqs)
+				}
+			}
+		})
+	}
+
+	t.Run("stop conditions", func(t *testing.T) {
+		atk := NewAttacker(Workers(1), MaxWorkers(1), TickQueue(0), StopOn(ConsecutiveFailures{N: 3}))
+
+		var dropped int
+		results := atk.Attack(tr, rate, 300*time.Millisecond, "")
+		for res := range results {
+			if res.Error == ErrTickDropped.Error() {
+				dropped++
+			}
+		}
+
+		if dropped < 3 {
+			t.Fatalf("got %d dropped ticks, want at least 3", dropped)
+		}
+
+		if c := atk.Tripped(results); c != nil {
+			t.Errorf("dropped ticks tripped %s", c)
+		}
+	})
+}
+
 func TestTLSConfig(t *testing.T) {
 	atk := NewAttacker()
 	got := atk.client.Transport.(*http.Transport).TLSClientConfig
@@ -149,8 +281,69 @@ func TestLocalAddr(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker(LocalAddr(*addr))
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	atk.hit(tr, &attack{name: "", began: time.Now()})
+	res := atk.hit(tr, &attack{name: "", began: time.Now()})
+	if got, want := res.LocalAddr, addr.String(); got != want {
+		t.Fatalf("wrong recorded local address. got %v, want: %v", got, want)
+	}
+}
+
+func TestLocalAddrs(t *testing.T) {
+	t.Parallel()
+
+	var addrs []net.IPAddr
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		// Not every OS routes the whole loopback range.
+		ln, err := net.Listen("tcp", ip+":0")
+		if err != nil {
+			t.Skipf("can't bind to %s: %v", ip, err)
+		}
+		ln.Close()
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			seen = append(seen, host)
+			mu.Unlock()
+		}),
+	)
+	defer server.Close()
+
+	atk := NewAttacker(KeepAlive(false), LocalAddrs(addrs, false))
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	a := &attack{name: "", began: time.Now()}
+
+	var recorded []string
+	for i := 0; i < 2*len(addrs); i++ {
+		res := atk.hit(tr, a)
+		if res.Error != "" {
+			t.Fatal(res.Error)
+		}
+		recorded = append(recorded, res.LocalAddr)
+	}
+
+	var want []string
+	for i := 0; i < 2*len(addrs); i++ {
+		want = append(want, addrs[i%len(addrs)].String())
+	}
+
+	if diff := cmp.Diff(want, seen); diff != "" {
+		t.Errorf("unexpected source addresses (-want +got):\n%s", diff)
+	}
 
+	if diff := cmp.Diff(want, recorded); diff != "" {
+		t.Errorf("unexpected recorded local addresses (-want +got):\n%s", diff)
+	}
 }
 
 func TestKeepAlive(t *testing.T) {
@@ -295,6 +488,177 @@ func TestMaxBody(t *testing.T) {
 	}
 }
 
+func TestBodyCapture(t *testing.T) {
+	t.Parallel()
+
+	body := []byte("VEGETA")
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/error" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			w.Write(body)
+		}),
+	)
+	defer server.Close()
+
+	hash := xxhash.Sum64(body)
+	wantHash := hex.EncodeToString(binary.BigEndian.AppendUint64(nil, hash))
+
+	for _, tc := range []struct {
+		name    string
+		capture BodyCapture
+		maxBody int64
+		paths   []string
+		want    []bool // Whether each path's body is captured.
+	}{
+		{
+			name:    "all by default",
+			maxBody: 3,
+			paths:   []string{"/", "/error"},
+			want:    []bool{true, true},
+		},
+		{
+			name:    "errors",
+			capture: BodyCapture{Errors: true},
+			maxBody: -1,
+			paths:   []string{"/", "/error", "/"},
+			want:    []bool{false, true, false},
+		},
+		{
+			name:    "first per code",
+			capture: BodyCapture{FirstPerCode: 2},
+			maxBody: -1,
+			paths:   []string{"/", "/error", "/", "/", "/error", "/error"},
+			want:    []bool{true, true, true, false, true, false},
+		},
+		{
+			name:    "sample none",
+			capture: BodyCapture{Sample: 1e-12},
+			maxBody: -1,
+			paths:   []string{"/", "/error"},
+			want:    []bool{false, false},
+		},
+		{
+			name:    "sample all",
+			capture: BodyCapture{Sample: 1},
+			maxBody: -1,
+			paths:   []string{"/", "/error"},
+			want:    []bool{true, true},
+		},
+	} {
+		atk := NewAttacker(CaptureBodies(tc.capture), MaxBody(tc.maxBody))
+		a := &attack{name: "", began: time.Now()}
+		for i, path := range tc.paths {
+			tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL + path})
+			res := atk.hit(tr, a)
+
+			want := []byte(nil)
+			if tc.want[i] {
+				want = body
+				if tc.maxBody >= 0 {
+					want = body[:tc.maxBody]
+				}
+			}
+
+			if !bytes.Equal(res.Body, want) {
+				t.Errorf("%s: %d %s: got body %q, want %q", tc.name, i, path, res.Body, want)
+			}
+
+			if res.BytesIn != uint64(len(body)) || res.BodyHash != wantHash {
+				t.Errorf("%s: %d %s: got bytes in %d and hash %s, want %d and %s",
+					tc.name, i, path, res.BytesIn, res.BodyHash, len(body), wantHash)
+			}
+		}
+	}
+
+	// Every response counts towards FirstPerCode, including those whose body
+	// is captured by another criterion.
+	atk := NewAttacker(CaptureBodies(BodyCapture{Sample: 1, FirstPerCode: 1}))
+	a := &attack{name: "", began: time.Now()}
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	for i := 0; i < 3; i++ {
+		atk.hit(tr, a)
+	}
+
+	if got := a.codes[http.StatusOK]; got != 3 {
+		t.Errorf("got %d responses counted towards FirstPerCode, want 3", got)
+	}
+}
+
+func TestTargetOverrides(t *testing.T) {
+	t.Parallel()
+
+	body := []byte("VEGETA")
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/slow":
+				time.Sleep(200 * time.Millisecond)
+			case "/redirect":
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			}
+			w.Write(body)
+		}),
+	)
+	defer server.Close()
+
+	atk := NewAttacker(Timeout(50*time.Millisecond), Redirects(0), MaxBody(1))
+
+	for _, tc := range []struct {
+		name string
+		tgt  Target
+		code uint16
+		body string
+		err  string
+	}{
+		{
+			name: "attacker timeout",
+			tgt:  Target{Method: "GET", URL: server.URL + "/slow"},
+			err:  "Client.Timeout exceeded",
+		},
+		{
+			name: "target timeout",
+			tgt:  Target{Method: "GET", URL: server.URL + "/slow", Timeout: ptr(time.Second)},
+			code: 200,
+			body: "V",
+		},
+		{
+			name: "attacker redirects",
+			tgt:  Target{Method: "GET", URL: server.URL + "/redirect"},
+			err:  "stopped after 0 redirects",
+		},
+		{
+			name: "target redirects",
+			tgt:  Target{Method: "GET", URL: server.URL + "/redirect", Redirects: ptr(1)},
+			code: 200,
+			body: "V",
+		},
+		{
+			name: "target max body",
+			tgt:  Target{Method: "GET", URL: server.URL, MaxBody: ptr(DefaultMaxBody)},
+			code: 200,
+			body: string(body),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := atk.hit(NewStaticTargeter(tc.tgt), &attack{name: "", began: time.Now()})
+			if !strings.Contains(res.Error, tc.err) || (tc.err == "" && res.Error != "") {
+				t.Errorf("got error %q, want %q", res.Error, tc.err)
+			}
+
+			if res.Code != tc.code {
+				t.Errorf("got code %d, want %d", res.Code, tc.code)
+			}
+
+			if got := string(res.Body); got != tc.body {
+				t.Errorf("got body %q, want %q", got, tc.body)
+			}
+		})
+	}
+}
+
 func TestUnixSocket(t *testing.T) {
 	t.Parallel()
 	body := []byte("IT'S A UNIX SYSTEM, I KNOW THIS")
@@ -411,6 +775,141 @@ func TestVegetaHeaders(t *testing.T) {
 	}
 }
 
+func TestAttackerStats(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	a := NewAttacker(Workers(1), MaxWorkers(2))
+
+	if stats := a.Stats(); len(stats) != 0 {
+		t.Fatalf("got stats %+v before attacking, want none", stats)
+	}
+
+	res := a.Attack(tr, Rate{Freq: 100, Per: time.Second}, 0, "stats")
+
+	// Both workers get stuck on their requests, so the third hit stays due.
+	var s AttackStats
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		stats := a.Stats()
+		if len(stats) != 1 {
+			t.Fatalf("got %d stats, want 1", len(stats))
+		}
+
+		if s = stats[0]; s.InFlight == 2 && s.Lag >= 50*time.Millisecond {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("attack didn't saturate: %+v", s)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if s.Name != "stats" || s.Workers != 2 || s.Hits != 2 || s.TargetRate != 100 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	if have, want := s.Rate(), 2/s.Elapsed.Seconds(); have != want {
+		t.Errorf("rate: have %v, want %v", have, want)
+	}
+
+	a.Stop()
+	close(release)
+	for range res {
+	}
+
+	// The final stats of finished attacks are kept.
+	stats := a.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("got %d stats after attacking, want 1", len(stats))
+	}
+
+	done := stats[0]
+	if !done.Done || done.Hits < 2 || done.Workers != 0 || done.InFlight != 0 || done.TargetRate != 0 || done.Elapsed < s.Elapsed {
+		t.Errorf("unexpected final stats: %+v", done)
+	}
+
+	if again := a.Stats(); again[0] != done {
+		t.Errorf("final stats changed from %+v to %+v", done, again[0])
+	}
+
+	// The hits of finished attacks with the same name are added up.
+	for range a.Attack(tr, Rate{Freq: 100, Per: time.Second}, 50*time.Millisecond, "stats") {
+	}
+
+	stats = a.Stats()
+	if len(stats) != 1 || !stats[0].Done || stats[0].Hits <= done.Hits || !stats[0].Began.After(done.Began) {
+		t.Errorf("got stats %+v after attacking again, want the total of %+v and more", stats, done)
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, strings.Join(r.Header.Values("Traceparent"), ","))
+		}),
+	)
+
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{
+		Method: "GET",
+		URL:    server.URL,
+		Header: http.Header{"traceparent": []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}},
+	})
+
+	atk := &attack{name: "trace", began: time.Now()}
+
+	res := NewAttacker().hit(tr, atk)
+	if have, want := string(res.Body), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"; have != want {
+		t.Errorf("disabled traceparent: have %q, want %q", have, want)
+	}
+
+	if res.TraceID != "" || res.SpanID != "" {
+		t.Errorf("disabled trace ID: have %q, want none", res.TraceID)
+	}
+
+	a := NewAttacker(TraceContext(true))
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		res := a.hit(tr, atk)
+
+		if len(res.TraceID) != 32 || seen[res.TraceID] {
+			t.Fatalf("bad or repeated trace ID %q", res.TraceID)
+		}
+		seen[res.TraceID] = true
+
+		if _, err := hex.DecodeString(res.TraceID); err != nil {
+			t.Fatalf("bad trace ID %q: %v", res.TraceID, err)
+		}
+
+		if len(res.SpanID) != 16 || res.SpanID == res.TraceID[16:] {
+			t.Fatalf("bad span ID %q of trace ID %q", res.SpanID, res.TraceID)
+		}
+
+		if _, err := hex.DecodeString(res.SpanID); err != nil {
+			t.Fatalf("bad span ID %q: %v", res.SpanID, err)
+		}
+
+		// The target's traceparent header is replaced, whatever its case.
+		want := "00-" + res.TraceID + "-" + res.SpanID + "-01"
+		if have := string(res.Body); have != want {
+			t.Errorf("traceparent: have %q, want %q", have, want)
+		}
+	}
+}
+
 // https://github.com/tsenart/vegeta/issues/649
 func TestDNSCaching_Issue649(t *testing.T) {
 	defer func() {
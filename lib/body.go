@@ -0,0 +1,111 @@
+package vegeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+	"os"
+)
+
+// bodySource opens a new reader of a request body of a known size on every
+// request, as well as on every call of the request's GetBody, which is
+// used to send the body again on redirects and retries.
+type bodySource struct {
+	open func() (io.ReadCloser, error)
+	size int64
+}
+
+// fileBody returns the source of a body streamed from the named file.
+func fileBody(name string) (*bodySource, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bodySource{
+		size: fi.Size(),
+		open: func() (io.ReadCloser, error) { return os.Open(name) },
+	}, nil
+}
+
+// randomBody returns the source of a body of n pseudo-random bytes,
+// generated as they're sent.
+func randomBody(n int64) *bodySource {
+	return &bodySource{
+		size: n,
+		open: func() (io.ReadCloser, error) {
+			rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+			return io.NopCloser(io.LimitReader(randomReader{rng}, n)), nil
+		},
+	}
+}
+
+type randomReader struct{ rng *rand.Rand }
+
+func (r randomReader) Read(p []byte) (int, error) {
+	var word [8]byte
+	for i := 0; i < len(p); i += len(word) {
+		binary.LittleEndian.PutUint64(word[:], r.rng.Uint64())
+		copy(p[i:], word[:])
+	}
+	return len(p), nil
+}
+
+// bodySegment is either in-memory data or the content of a file.
+type bodySegment struct {
+	data []byte
+	file string
+}
+
+// segmentsReader reads the given segments one after the other, opening
+// files only when they're reached.
+type segmentsReader struct {
+	segs []bodySegment
+	cur  io.Reader
+	f    *os.File
+}
+
+func (r *segmentsReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.segs) == 0 {
+				return 0, io.EOF
+			}
+
+			seg := r.segs[0]
+			r.segs = r.segs[1:]
+
+			if seg.file == "" {
+				r.cur = bytes.NewReader(seg.data)
+			} else {
+				f, err := os.Open(seg.file)
+				if err != nil {
+					return 0, err
+				}
+				r.cur, r.f = f, f
+			}
+		}
+
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur = nil
+			if err = r.Close(); err != nil {
+				return n, err
+			} else if n == 0 {
+				continue
+			}
+		}
+
+		return n, err
+	}
+}
+
+func (r *segmentsReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
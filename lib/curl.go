@@ -0,0 +1,484 @@
+package vegeta
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CurlTargetFormat is the human readable identifier for the curl target format.
+const CurlTargetFormat = "curl"
+
+// NewCurlTargeter returns a new Targeter that decodes one Target from the
+// given io.Reader on every invocation, out of a curl command such as the
+// ones copied from browser developer tools. Commands may span many lines with
+// trailing backslashes. Empty lines and lines starting with # are skipped.
+//
+//	curl 'https://goku/things' -H 'Accept: application/json' --compressed
+//	curl -X POST https://goku/things \
+//	  -u goku:kamehameha \
+//	  --data-binary @/path/to/body/file
+//
+// The supported options are -X, -H, -d, --data-raw, --data-binary, -F, -u, -A,
+// -e, -b, -G, -I, --url, --compressed and --max-time, which sets the Target's
+// timeout. Options that don't affect the request, such as -s or -k, are ignored.
+//
+// body will be set as the Target's body if no body is provided.
+// hdr will be merged with the each Target's headers.
+func NewCurlTargeter(src io.Reader, body []byte, hdr http.Header) Targeter {
+	var mu sync.Mutex
+	sc := bufio.NewScanner(src)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	return func(tgt *Target) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if tgt == nil {
+			return ErrNilTarget
+		}
+
+		var cmd strings.Builder
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if cmd.Len() == 0 && (line == "" || line[0] == '#') {
+				continue
+			}
+
+			if cont, ok := strings.CutSuffix(line, `\`); ok {
+				cmd.WriteString(cont)
+				cmd.WriteByte(' ')
+				continue
+			}
+
+			cmd.WriteString(line)
+			break
+		}
+
+		if err = sc.Err(); err != nil {
+			return err
+		} else if cmd.Len() == 0 {
+			return ErrNoTargets
+		}
+
+		t, err := parseCurl(cmd.String())
+		if err != nil {
+			return err
+		}
+
+		tgt.Method = t.Method
+		tgt.URL = t.URL
+		tgt.Timeout, tgt.Redirects, tgt.MaxBody = t.Timeout, nil, nil
+		tgt.BodyFile, tgt.BodyRandom = "", 0
+		tgt.Multipart = t.Multipart
+		if tgt.Body = body; len(t.Body) > 0 {
+			tgt.Body = t.Body
+		}
+
+		tgt.Header = http.Header{}
+		addHeader(tgt.Header, hdr)
+		addHeader(tgt.Header, t.Header)
+
+		return nil
+	}
+}
+
+// curl options that take an argument but don't affect the request.
+var curlIgnoredArgs = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"--connect-timeout": true, "--retry": true, "--retry-delay": true,
+	"--retry-max-time": true, "-c": true, "--cookie-jar": true,
+	"-x": true, "--proxy": true, "--resolve": true, "--cacert": true,
+	"--cert": true, "--key": true, "-r": true, "--range": true,
+}
+
+// curl options without an argument that don't affect the request.
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-k": true, "--insecure": true, "-L": true, "--location": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-f": true, "--fail": true, "-#": true, "--progress-bar": true,
+	"--http1.1": true, "--http2": true, "--globoff": true, "-g": true,
+	"-N": true, "--no-buffer": true,
+}
+
+// curl options whose short form takes an argument.
+const curlShortArgs = "XHdubAeomwcxrFm"
+
+// parseCurl parses a curl command into a Target.
+func parseCurl(cmd string) (Target, error) {
+	args, err := shellWords(cmd)
+	if err != nil {
+		return Target{}, fmt.Errorf("bad curl command: %w", err)
+	}
+
+	if len(args) == 0 || args[0] != "curl" {
+		return Target{}, fmt.Errorf("bad curl command: %s", cmd)
+	}
+
+	var (
+		tgt         = Target{Header: http.Header{}}
+		data        []string
+		get         bool
+		head        bool
+		positionals []string
+	)
+
+	for i := 1; i < len(args); i++ {
+		name, value, hasValue := args[i], "", false
+
+		switch {
+		case name == "--":
+			positionals = append(positionals, args[i+1:]...)
+			i = len(args)
+			continue
+		case strings.HasPrefix(name, "--"):
+			if n, v, ok := strings.Cut(name, "="); ok {
+				name, value, hasValue = n, v, true
+			}
+		case strings.HasPrefix(name, "-") && len(name) > 1:
+			// Short options can be combined (-sSL) and take attached
+			// arguments (-XPOST).
+			for j := 1; j < len(name); j++ {
+				if strings.IndexByte(curlShortArgs, name[j]) >= 0 {
+					if j+1 < len(name) {
+						value, hasValue = name[j+1:], true
+					}
+					name = "-" + name[j:j+1]
+					break
+				}
+
+				if j == len(name)-1 {
+					name = "-" + name[j:]
+					break
+				}
+
+				if flag := "-" + name[j:j+1]; !curlIgnoredFlags[flag] && flag != "-G" && flag != "-I" {
+					return tgt, fmt.Errorf("bad curl command: unsupported option %s", flag)
+				}
+
+				switch name[j] {
+				case 'G':
+					get = true
+				case 'I':
+					head = true
+				}
+			}
+		default:
+			positionals = append(positionals, name)
+			continue
+		}
+
+		arg := func() (string, error) {
+			if hasValue {
+				return value, nil
+			} else if i+1 >= len(args) {
+				return "", fmt.Errorf("bad curl command: option %s requires an argument", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var v string
+		switch name {
+		case "-X", "--request":
+			if v, err = arg(); err == nil {
+				tgt.Method = strings.ToUpper(v)
+			}
+		case "-H", "--header":
+			if v, err = arg(); err == nil {
+				k, val, ok := strings.Cut(v, ":")
+				if !ok || strings.TrimSpace(k) == "" {
+					return tgt, fmt.Errorf("bad curl command: bad header: %s", v)
+				}
+				// Names are canonicalized, since devtools copy them in lowercase.
+				tgt.Header.Add(strings.TrimSpace(k), strings.TrimSpace(val))
+			}
+		case "-d", "--data", "--data-ascii":
+			if v, err = arg(); err == nil {
+				if v, err = curlData(v, true); err == nil {
+					data = append(data, v)
+				}
+			}
+		case "--data-binary":
+			if v, err = arg(); err == nil {
+				if v, err = curlData(v, false); err == nil {
+					data = append(data, v)
+				}
+			}
+		case "--data-raw":
+			if v, err = arg(); err == nil {
+				data = append(data, v)
+			}
+		case "-F", "--form":
+			if v, err = arg(); err == nil {
+				var p Part
+				if p, err = ParsePart(v); err == nil {
+					tgt.Multipart = append(tgt.Multipart, p)
+				}
+			}
+		case "--form-string":
+			if v, err = arg(); err == nil {
+				name, value, _ := strings.Cut(v, "=")
+				tgt.Multipart = append(tgt.Multipart, Part{Name: name, Value: value})
+			}
+		case "--data-urlencode":
+			if v, err = arg(); err == nil {
+				data = append(data, curlURLEncode(v))
+			}
+		case "-u", "--user":
+			if v, err = arg(); err == nil {
+				tgt.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+			}
+		case "-A", "--user-agent":
+			if v, err = arg(); err == nil {
+				tgt.Header.Set("User-Agent", v)
+			}
+		case "-e", "--referer":
+			if v, err = arg(); err == nil {
+				tgt.Header.Set("Referer", v)
+			}
+		case "-b", "--cookie":
+			if v, err = arg(); err == nil {
+				tgt.Header.Add("Cookie", v)
+			}
+		case "-m", "--max-time":
+			if v, err = arg(); err == nil {
+				var secs float64
+				if secs, err = strconv.ParseFloat(v, 64); err == nil {
+					timeout := time.Duration(secs * float64(time.Second))
+					tgt.Timeout = &timeout
+				}
+			}
+		case "--url":
+			if v, err = arg(); err == nil {
+				positionals = append(positionals, v)
+			}
+		case "--compressed":
+			if !hasHeader(tgt.Header, "Accept-Encoding") {
+				tgt.Header.Set("Accept-Encoding", "deflate, gzip")
+			}
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		default:
+			switch {
+			case curlIgnoredArgs[name]:
+				_, err = arg()
+			case curlIgnoredFlags[name]:
+			default:
+				err = fmt.Errorf("bad curl command: unsupported option %s", name)
+			}
+		}
+
+		if err != nil {
+			return tgt, err
+		}
+	}
+
+	if len(positionals) != 1 {
+		return tgt, fmt.Errorf("bad curl command: want one URL, got %d", len(positionals))
+	}
+
+	tgt.URL = positionals[0]
+	if !strings.Contains(tgt.URL, "://") {
+		tgt.URL = "http://" + tgt.URL
+	}
+
+	u, err := url.ParseRequestURI(tgt.URL)
+	if err != nil {
+		return tgt, fmt.Errorf("bad curl command: bad URL: %s, %w", tgt.URL, err)
+	}
+
+	switch {
+	case get && len(data) > 0:
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += strings.Join(data, "&")
+		tgt.URL = u.String()
+	case len(data) > 0:
+		tgt.Body = []byte(strings.Join(data, "&"))
+		if !hasHeader(tgt.Header, "Content-Type") {
+			tgt.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	if tgt.Method == "" {
+		switch {
+		case head:
+			tgt.Method = http.MethodHead
+		case len(data) > 0 && !get, len(tgt.Multipart) > 0:
+			tgt.Method = http.MethodPost
+		default:
+			tgt.Method = http.MethodGet
+		}
+	}
+
+	return tgt, nil
+}
+
+// curlData returns the data of a curl data option, read from a file if it
+// starts with @. As curl does, newlines are stripped from files when strip
+// is true.
+func curlData(v string, strip bool) (string, error) {
+	name, ok := strings.CutPrefix(v, "@")
+	if !ok {
+		return v, nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("bad curl command: bad body: %w", err)
+	}
+
+	if strip {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(string(data)), nil
+	}
+
+	return string(data), nil
+}
+
+// curlURLEncode URL encodes the content of a --data-urlencode option
+// which may be prefixed by a name and an equal sign.
+func curlURLEncode(v string) string {
+	name, content, ok := strings.Cut(v, "=")
+	if !ok {
+		return url.QueryEscape(v)
+	} else if name == "" {
+		return url.QueryEscape(content)
+	}
+	return name + "=" + url.QueryEscape(content)
+}
+
+// shellWords splits a command line into words following the quoting rules
+// of POSIX shells, as well as bash's ANSI-C quoting ($'...').
+func shellWords(s string) ([]string, error) {
+	var (
+		words []string
+		word  strings.Builder
+		inW   bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inW {
+				words = append(words, word.String())
+				word.Reset()
+				inW = false
+			}
+		case c == '\\':
+			inW = true
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+		case c == '\'':
+			inW = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			inW = true
+			n, err := ansiCQuoted(s[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+		case c == '"':
+			inW = true
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+		default:
+			inW = true
+			word.WriteByte(c)
+		}
+	}
+
+	if inW {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// ansiCQuoted writes the unescaped content of the given ANSI-C quoted string,
+// which starts after the opening $' sequence, to the given word. It returns
+// the index of the closing quote.
+func ansiCQuoted(s string, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			return i, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				word.WriteByte('\n')
+			case 't':
+				word.WriteByte('\t')
+			case 'r':
+				word.WriteByte('\r')
+			case 'x':
+				j := i + 1
+				for j < len(s) && j < i+3 && isHexDigit(s[j]) {
+					j++
+				}
+				if j == i+1 {
+					word.WriteString(`\x`)
+					continue
+				}
+				n, _ := strconv.ParseUint(s[i+1:j], 16, 8)
+				word.WriteByte(byte(n))
+				i = j - 1
+			case 'u':
+				j := i + 1
+				for j < len(s) && j < i+5 && isHexDigit(s[j]) {
+					j++
+				}
+				if j == i+1 {
+					word.WriteString(`\u`)
+					continue
+				}
+				n, _ := strconv.ParseUint(s[i+1:j], 16, 32)
+				word.WriteRune(rune(n))
+				i = j - 1
+			case '\\', '\'', '"', '?':
+				word.WriteByte(e)
+			default:
+				word.WriteByte('\\')
+				word.WriteByte(e)
+			}
+		default:
+			word.WriteByte(c)
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated ANSI-C quote")
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
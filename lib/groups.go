@@ -0,0 +1,197 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// A GroupKey returns the key of the group a Result belongs to.
+type GroupKey func(*Result) string
+
+// GroupByURL groups Results by their request URL.
+func GroupByURL(r *Result) string { return r.URL }
+
+// GroupByMethod groups Results by their request method.
+func GroupByMethod(r *Result) string { return r.Method }
+
+// GroupByStatus groups Results by their response status code.
+func GroupByStatus(r *Result) string { return strconv.Itoa(int(r.Code)) }
+
+// GroupByAttack groups Results by the name of their attack.
+func GroupByAttack(r *Result) string { return r.Attack }
+
+// GroupByHeader returns a GroupKey which groups Results by the value of
+// the given response header.
+func GroupByHeader(name string) GroupKey {
+	name = http.CanonicalHeaderKey(name)
+	return func(r *Result) string { return r.Headers.Get(name) }
+}
+
+// OtherGroup is the key of the group of the Results which don't fit in
+// Groups once the maximum number of groups is reached.
+const OtherGroup = "(other)"
+
+// DefaultMaxGroups is the default maximum number of groups of Groups.
+const DefaultMaxGroups = 100
+
+// Groups is a Report which splits Results into groups by their key, each
+// with its own Report. Once there are Max groups, the Results of further
+// keys are added to the OtherGroup, so that Results with unbounded keys,
+// such as URLs with IDs, don't exhaust memory.
+type Groups struct {
+	// Key returns the group key of a Result.
+	Key GroupKey
+	// Max is the maximum number of groups, including the OtherGroup.
+	// It defaults to DefaultMaxGroups.
+	Max int
+	// New returns the empty Report of a new group.
+	New func() Report
+
+	// Reports holds the Report of each group by key.
+	Reports map[string]Report
+}
+
+// NewGroups returns new Groups of Results with the given key, up to max
+// groups, each with a Report returned by new.
+func NewGroups(key GroupKey, max int, new func() Report) *Groups {
+	return &Groups{Key: key, Max: max, New: new}
+}
+
+// Add implements the Add method of the Report interface by adding the given
+// Result to the Report of its group.
+func (g *Groups) Add(r *Result) {
+	if g.Reports == nil {
+		g.Reports = map[string]Report{}
+	}
+
+	max := g.Max
+	if max <= 0 {
+		max = DefaultMaxGroups
+	}
+
+	key := g.Key(r)
+	rep, ok := g.Reports[key]
+	if !ok && len(g.Reports) >= max-1 {
+		// The last group is kept for the Results of all other keys.
+		key = OtherGroup
+		rep, ok = g.Reports[key]
+	}
+
+	if !ok {
+		rep = g.New()
+		g.Reports[key] = rep
+	}
+
+	rep.Add(r)
+}
+
+// Close implements the Close method of the Report interface by closing the
+// Report of every group.
+func (g *Groups) Close() {
+	for _, rep := range g.Reports {
+		if c, ok := rep.(Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// Keys returns the sorted keys of the groups, with the OtherGroup last.
+func (g *Groups) Keys() []string {
+	keys := make([]string, 0, len(g.Reports))
+	for key := range g.Reports {
+		if key != OtherGroup {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	if _, ok := g.Reports[OtherGroup]; ok {
+		keys = append(keys, OtherGroup)
+	}
+
+	return keys
+}
+
+// NewGroupedTextReporter returns a Reporter that writes out Groups of Metrics
+// as an aligned, formatted text table with a row per group.
+func NewGroupedTextReporter(g *Groups) Reporter {
+	return func(w io.Writer) (err error) {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
+		if _, err = fmt.Fprintf(tw, "Group\tRequests\tRate\tSuccess\tMin\tMean\t50\t90\t95\t99\tMax\tBytes In\tBytes Out\n"); err != nil {
+			return err
+		}
+
+		for _, key := range g.Keys() {
+			m, ok := g.Reports[key].(*Metrics)
+			if !ok {
+				return fmt.Errorf("group %q: %T isn't *Metrics", key, g.Reports[key])
+			}
+
+			if key == "" {
+				key = "-"
+			}
+
+			if _, err = fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f%%\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n",
+				key, m.Requests, m.Rate, m.Success*100,
+				RoundDuration(m.Latencies.Min),
+				RoundDuration(m.Latencies.Mean),
+				RoundDuration(m.Latencies.P50),
+				RoundDuration(m.Latencies.P90),
+				RoundDuration(m.Latencies.P95),
+				RoundDuration(m.Latencies.P99),
+				RoundDuration(m.Latencies.Max),
+				m.BytesIn.Total, m.BytesOut.Total,
+			); err != nil {
+				return err
+			}
+		}
+
+		return tw.Flush()
+	}
+}
+
+// NewGroupedJSONReporter returns a Reporter that writes out Groups as a JSON
+// array with the key and the Report of each group.
+func NewGroupedJSONReporter(g *Groups) Reporter {
+	type group struct {
+		Group  string `json:"group"`
+		Report Report `json:"metrics"`
+	}
+
+	return func(w io.Writer) error {
+		groups := make([]group, 0, len(g.Reports))
+		for _, key := range g.Keys() {
+			groups = append(groups, group{Group: key, Report: g.Reports[key]})
+		}
+		return json.NewEncoder(w).Encode(groups)
+	}
+}
+
+// NewGroupedReporter returns a Reporter that writes out the Report of each
+// of the Groups with the Reporter returned by the given function, preceded
+// by a line with its key.
+func NewGroupedReporter(g *Groups, reporter func(Report) Reporter) Reporter {
+	return func(w io.Writer) error {
+		for i, key := range g.Keys() {
+			sep := "\n"
+			if i == 0 {
+				sep = ""
+			}
+
+			if _, err := fmt.Fprintf(w, "%sGroup: %s\n", sep, key); err != nil {
+				return err
+			}
+
+			if err := reporter(g.Reports[key]).Report(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package vegeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupKeys(t *testing.T) {
+	t.Parallel()
+
+	r := &Result{
+		Attack:  "checkout",
+		Code:    503,
+		Method:  "POST",
+		URL:     "http://goku/orders",
+		Headers: http.Header{"X-Served-By": []string{"node-1"}},
+	}
+
+	for _, tc := range []struct {
+		name string
+		key  GroupKey
+		want string
+	}{
+		{"url", GroupByURL, "http://goku/orders"},
+		{"method", GroupByMethod, "POST"},
+		{"status", GroupByStatus, "503"},
+		{"attack", GroupByAttack, "checkout"},
+		{"header", GroupByHeader("x-served-by"), "node-1"},
+		{"missing header", GroupByHeader("X-Cache"), ""},
+	} {
+		if got := tc.key(r); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	t.Parallel()
+
+	g := NewGroups(GroupByURL, 3, func() Report { return &Metrics{} })
+	for i, url := range []string{"/b", "/a", "/b", "/c", "/d", "/a", "/c"} {
+		g.Add(&Result{
+			URL:       url,
+			Code:      200,
+			Timestamp: time.Unix(int64(i), 0),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+		})
+	}
+	g.Close()
+
+	if got, want := g.Keys(), []string{"/a", "/b", OtherGroup}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got keys %v, want %v", got, want)
+	}
+
+	for key, want := range map[string]uint64{"/a": 2, "/b": 2, OtherGroup: 3} {
+		if got := g.Reports[key].(*Metrics).Requests; got != want {
+			t.Errorf("%s: got %d requests, want %d", key, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewGroupedTextReporter(g).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "Group") ||
+		!strings.HasPrefix(lines[1], "/a ") || !strings.HasPrefix(lines[3], OtherGroup+" ") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := NewGroupedJSONReporter(g).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []struct {
+		Group   string  `json:"group"`
+		Metrics Metrics `json:"metrics"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	} else if len(groups) != 3 || groups[1].Group != "/b" || groups[1].Metrics.Requests != 2 {
+		t.Errorf("unexpected JSON report:\n%s", buf.String())
+	}
+
+	h := NewGroups(GroupByURL, 0, func() Report { return &Histogram{Buckets: Buckets{0, 5 * time.Millisecond}} })
+	h.Add(&Result{URL: "/a", Latency: time.Millisecond})
+	h.Add(&Result{URL: "/b", Latency: 10 * time.Millisecond})
+
+	buf.Reset()
+	err := NewGroupedReporter(h, func(r Report) Reporter { return NewHistogramReporter(r.(*Histogram)) }).Report(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); !strings.HasPrefix(got, "Group: /a\nBucket") || !strings.Contains(got, "\n\nGroup: /b\nBucket") {
+		t.Errorf("unexpected grouped histogram report:\n%s", got)
+	}
+}
@@ -0,0 +1,461 @@
+package vegeta
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HARTargetFormat is the human readable identifier for the HAR target format.
+const HARTargetFormat = "har"
+
+// HAROptions define which entries of a HAR document are read as Targets
+// and how.
+type HAROptions struct {
+	// Hosts, when not empty, restricts Targets to the entries whose request
+	// URL has one of the given hosts, with or without port.
+	Hosts []string
+	// MimeTypes, when not empty, restricts Targets to the entries whose
+	// response content has one of the given mime types. A mime type may
+	// have a wildcard subtype, such as "image/*".
+	MimeTypes []string
+	// DropCookies drops the Cookie header of requests.
+	DropCookies bool
+}
+
+// NewHARTargeter returns a new Targeter that decodes one Target out of the
+// entries of the HAR document read from the given io.Reader on every
+// invocation, in the order they were recorded. The document is read in full
+// on the first invocation.
+//
+// Each Target has the method, URL, headers and body (postData) of the
+// request of an entry. HTTP/2 pseudo-headers are dropped.
+//
+// body will be set as the Target's body if no body is provided in an entry.
+// hdr will be merged with the each Target's headers.
+func NewHARTargeter(src io.Reader, body []byte, hdr http.Header, opts HAROptions) Targeter {
+	var (
+		mu   sync.Mutex
+		once sync.Once
+		tgts []Target
+		err  error
+		i    int
+	)
+
+	return func(tgt *Target) error {
+		if tgt == nil {
+			return ErrNilTarget
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		once.Do(func() { tgts, _, err = ReadHARTargets(src, opts) })
+		if err != nil {
+			return err
+		} else if i >= len(tgts) {
+			return ErrNoTargets
+		}
+
+		t := tgts[i]
+		i++
+
+		tgt.Method = t.Method
+		tgt.URL = t.URL
+		tgt.Timeout, tgt.Redirects, tgt.MaxBody = nil, nil, nil
+		tgt.BodyFile, tgt.BodyRandom = "", 0
+		tgt.Multipart = nil
+		if tgt.Body = body; len(t.Body) > 0 {
+			tgt.Body = t.Body
+		}
+
+		tgt.Header = http.Header{}
+		for k, vs := range hdr {
+			tgt.Header[k] = append(tgt.Header[k], vs...)
+		}
+
+		addHeader(tgt.Header, t.Header)
+
+		return nil
+	}
+}
+
+// ReadHARTargets reads the HAR document from the given io.Reader and returns
+// the Targets of its entries selected by the given options, along with the
+// times at which their requests were originally started, both sorted by the
+// latter. The times can be used with NewReplayPacer to reproduce the timing of
+// the recorded session.
+func ReadHARTargets(src io.Reader, opts HAROptions) ([]Target, []time.Time, error) {
+	var doc harDocument
+	if err := json.NewDecoder(src).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("bad HAR: %w", err)
+	}
+
+	var (
+		tgts    []Target
+		started []time.Time
+	)
+
+	for i, e := range doc.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad HAR entry %d URL: %w", i, err)
+		}
+
+		if !opts.matchHost(u) || !opts.matchMimeType(e.Response.Content.MimeType) {
+			continue
+		}
+
+		tgt, err := e.Request.target(opts.DropCookies)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad HAR entry %d: %w", i, err)
+		}
+
+		tgts = append(tgts, tgt)
+		started = append(started, e.StartedDateTime)
+	}
+
+	if len(tgts) == 0 {
+		return nil, nil, ErrNoTargets
+	}
+
+	sortByTime(tgts, started)
+	return tgts, started, nil
+}
+
+func (o HAROptions) matchHost(u *url.URL) bool {
+	if len(o.Hosts) == 0 {
+		return true
+	}
+
+	for _, h := range o.Hosts {
+		if strings.EqualFold(h, u.Host) || strings.EqualFold(h, u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o HAROptions) matchMimeType(mt string) bool {
+	if len(o.MimeTypes) == 0 {
+		return true
+	}
+
+	if parsed, _, err := mime.ParseMediaType(mt); err == nil {
+		mt = parsed
+	}
+
+	for _, want := range o.MimeTypes {
+		want = strings.ToLower(strings.TrimSpace(want))
+		if prefix, ok := strings.CutSuffix(want, "/*"); ok {
+			if strings.HasPrefix(mt, prefix+"/") {
+				return true
+			}
+		} else if mt == want {
+			return true
+		}
+	}
+
+	return false
+}
+
+// harDocument is the subset of the HAR 1.2 format vegeta reads and writes.
+// See http://www.softwareishard.com/blog/har-12-spec/.
+type harDocument struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	// Custom fields, prefixed with an underscore as required by the spec.
+	Attack string `json:"_attack,omitempty"`
+	Seq    uint64 `json:"_seq"`
+	Error  string `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harNVP     `json:"cookies"`
+	Headers     []harNVP     `json:"headers"`
+	QueryString []harNVP     `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harNVP   `json:"cookies"`
+	Headers     []harNVP   `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings are durations in milliseconds. Vegeta only measures the total
+// latency of requests, so it is all accounted as waiting for the response.
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harPostData struct {
+	MimeType string   `json:"mimeType"`
+	Text     string   `json:"text"`
+	Encoding string   `json:"encoding,omitempty"`
+	Params   []harNVP `json:"params,omitempty"`
+}
+
+// harNVP is a HAR name and value pair, used for headers among others.
+type harNVP struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkippedHeaders are the recorded request headers which are managed by
+// the transport or are hop-by-hop, and so aren't replayed.
+var harSkippedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+func (r *harRequest) target(dropCookies bool) (Target, error) {
+	tgt := Target{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: http.Header{},
+	}
+
+	if tgt.Method == "" {
+		return tgt, ErrNoMethod
+	} else if tgt.URL == "" {
+		return tgt, ErrNoURL
+	}
+
+	var authority string
+	for _, h := range r.Headers {
+		// HTTP/2 captures record header names in lowercase, so they're
+		// canonicalized to not duplicate those set by net/http.
+		name := http.CanonicalHeaderKey(h.Name)
+		switch {
+		case name == ":authority":
+			authority = h.Value
+		case strings.HasPrefix(name, ":"): // Other HTTP/2 pseudo-headers
+		case harSkippedHeaders[name]:
+		case dropCookies && name == "Cookie":
+		case name == "Host":
+			// Sent as the request Host, rather than as a header.
+			tgt.Header[name] = []string{h.Value}
+		default:
+			tgt.Header[name] = append(tgt.Header[name], h.Value)
+		}
+	}
+
+	if _, ok := tgt.Header["Host"]; !ok && authority != "" {
+		tgt.Header["Host"] = []string{authority}
+	}
+
+	if r.PostData == nil {
+		return tgt, nil
+	}
+
+	switch pd := r.PostData; {
+	case pd.Encoding == "base64":
+		body, err := base64.StdEncoding.DecodeString(pd.Text)
+		if err != nil {
+			return tgt, fmt.Errorf("bad postData: %w", err)
+		}
+		tgt.Body = body
+	case pd.Text != "":
+		tgt.Body = []byte(pd.Text)
+	case len(pd.Params) > 0: // Only URL encoded forms are supported.
+		form := url.Values{}
+		for _, p := range pd.Params {
+			form.Add(p.Name, p.Value)
+		}
+		tgt.Body = []byte(form.Encode())
+	}
+
+	if _, ok := tgt.Header["Content-Type"]; !ok && r.PostData.MimeType != "" {
+		tgt.Header["Content-Type"] = []string{r.PostData.MimeType}
+	}
+
+	return tgt, nil
+}
+
+// addHeader adds the values of src to dst, under the keys of dst which are
+// equal to those of src case-insensitively, if any, so that differently
+// cased keys don't end up as duplicate headers.
+func addHeader(dst, src http.Header) {
+	for k, vs := range src {
+		for dk := range dst {
+			if strings.EqualFold(dk, k) {
+				k = dk
+				break
+			}
+		}
+		dst[k] = append(dst[k], vs...)
+	}
+}
+
+// hasHeader returns true if the given header has the given key, compared
+// case-insensitively.
+func hasHeader(hdr http.Header, key string) bool {
+	for k := range hdr {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHAREncoder returns an Encoder that writes Results as the entries of an
+// HAR 1.2 document to the given io.Writer as they are encoded, so that they
+// don't need to be held in memory. The returned end function writes the end of
+// the document and must be called after the last Result is encoded.
+//
+// Results don't record requests' headers and bodies, so entries lack them.
+// Response bodies that aren't valid UTF-8 are base64 encoded.
+func NewHAREncoder(w io.Writer) (enc Encoder, end func() error) {
+	begun := false
+	begin := func() error {
+		if begun {
+			_, err := io.WriteString(w, ",\n")
+			return err
+		}
+		begun = true
+		_, err := io.WriteString(w, `{"log":{"version":"1.2","creator":{"name":"vegeta","version":""},"entries":[`+"\n")
+		return err
+	}
+
+	enc = func(r *Result) error {
+		entry, err := json.Marshal(harEntryOf(r))
+		if err != nil {
+			return err
+		}
+
+		if err = begin(); err != nil {
+			return err
+		}
+
+		_, err = w.Write(entry)
+		return err
+	}
+
+	end = func() error {
+		if !begun {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "\n]}}\n")
+		return err
+	}
+
+	return enc, end
+}
+
+// harEntryOf returns the HAR entry of the given Result.
+func harEntryOf(r *Result) *harEntry {
+	latency := float64(r.Latency) / float64(time.Millisecond)
+	e := harEntry{
+		StartedDateTime: r.Timestamp,
+		Time:            latency,
+		Request: harRequest{
+			Method:      r.Method,
+			URL:         r.URL,
+			Cookies:     []harNVP{},
+			Headers:     []harNVP{},
+			QueryString: []harNVP{},
+			HeadersSize: -1,
+			BodySize:    int64(r.BytesOut),
+		},
+		Response: harResponse{
+			Status:      int(r.Code),
+			StatusText:  http.StatusText(int(r.Code)),
+			Cookies:     []harNVP{},
+			Headers:     []harNVP{},
+			RedirectURL: r.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    int64(r.BytesIn),
+		},
+		Timings: harTimings{Wait: latency},
+		Attack:  r.Attack,
+		Seq:     r.Seq,
+		Error:   r.Error,
+	}
+
+	if u, err := url.Parse(r.URL); err == nil {
+		for k, vs := range u.Query() {
+			for _, v := range vs {
+				e.Request.QueryString = append(e.Request.QueryString, harNVP{Name: k, Value: v})
+			}
+		}
+		sort.Slice(e.Request.QueryString, func(i, j int) bool {
+			return e.Request.QueryString[i].Name < e.Request.QueryString[j].Name
+		})
+	}
+
+	names := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		for _, v := range r.Headers[k] {
+			e.Response.Headers = append(e.Response.Headers, harNVP{Name: k, Value: v})
+		}
+	}
+
+	e.Response.Content = harContent{
+		Size:     int64(len(r.Body)),
+		MimeType: r.Headers.Get("Content-Type"),
+	}
+
+	if utf8.Valid(r.Body) {
+		e.Response.Content.Text = string(r.Body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(r.Body)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	return &e
+}
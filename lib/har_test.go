@@ -0,0 +1,337 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "startedDateTime": "2024-01-02T10:00:00.000Z",
+        "request": {
+          "method": "GET",
+          "url": "https://goku.dev/",
+          "headers": [
+            {"name": ":authority", "value": "goku.dev"},
+            {"name": "Accept", "value": "text/html"},
+            {"name": "Cookie", "value": "session=1"}
+          ]
+        },
+        "response": {"status": 200, "content": {"mimeType": "text/html; charset=utf-8"}}
+      },
+      {
+        "startedDateTime": "2024-01-02T10:00:00.250Z",
+        "request": {
+          "method": "POST",
+          "url": "https://api.goku.dev:8443/things",
+          "headers": [
+            {"name": "content-type", "value": "application/json"},
+            {"name": "host", "value": "api.goku.dev:8443"},
+            {"name": "content-length", "value": "15"},
+            {"name": "connection", "value": "keep-alive"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"name\":\"ball\"}"}
+        },
+        "response": {"status": 201, "content": {"mimeType": "application/json"}}
+      },
+      {
+        "startedDateTime": "2024-01-02T10:00:01.000Z",
+        "request": {
+          "method": "POST",
+          "url": "https://goku.dev/login",
+          "headers": [],
+          "postData": {
+            "mimeType": "application/x-www-form-urlencoded",
+            "params": [{"name": "user", "value": "goku"}, {"name": "pass", "value": "kamehameha"}]
+          }
+        },
+        "response": {"status": 302, "content": {"mimeType": ""}}
+      },
+      {
+        "startedDateTime": "2024-01-02T10:00:02.000Z",
+        "request": {"method": "GET", "url": "https://cdn.goku.dev/logo.png", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "image/png"}}
+      }
+    ]
+  }
+}`
+
+func TestReadHARTargets(t *testing.T) {
+	t.Parallel()
+
+	began := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	page := Target{
+		Method: "GET",
+		URL:    "https://goku.dev/",
+		Header: http.Header{"Accept": {"text/html"}, "Cookie": {"session=1"}, "Host": {"goku.dev"}},
+	}
+	api := Target{
+		Method: "POST",
+		URL:    "https://api.goku.dev:8443/things",
+		Body:   []byte(`{"name":"ball"}`),
+		Header: http.Header{"Content-Type": {"application/json"}, "Host": {"api.goku.dev:8443"}},
+	}
+	login := Target{
+		Method: "POST",
+		URL:    "https://goku.dev/login",
+		Body:   []byte("pass=kamehameha&user=goku"),
+		Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+	}
+	logo := Target{
+		Method: "GET",
+		URL:    "https://cdn.goku.dev/logo.png",
+		Header: http.Header{},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opts    HAROptions
+		targets []Target
+		started []time.Time
+		err     error
+	}{
+		{
+			name:    "all",
+			targets: []Target{page, api, login, logo},
+			started: []time.Time{began, began.Add(250 * time.Millisecond), began.Add(time.Second), began.Add(2 * time.Second)},
+		},
+		{
+			name: "drop cookies",
+			opts: HAROptions{DropCookies: true, Hosts: []string{"goku.dev"}},
+			targets: []Target{
+				{Method: "GET", URL: "https://goku.dev/", Header: http.Header{"Accept": {"text/html"}, "Host": {"goku.dev"}}},
+				login,
+			},
+			started: []time.Time{began, began.Add(time.Second)},
+		},
+		{
+			name:    "hosts with and without port",
+			opts:    HAROptions{Hosts: []string{"api.goku.dev:8443", "CDN.goku.dev"}},
+			targets: []Target{api, logo},
+			started: []time.Time{began.Add(250 * time.Millisecond), began.Add(2 * time.Second)},
+		},
+		{
+			name:    "mime types",
+			opts:    HAROptions{MimeTypes: []string{"text/html", "image/*"}},
+			targets: []Target{page, logo},
+			started: []time.Time{began, began.Add(2 * time.Second)},
+		},
+		{
+			name: "no matches",
+			opts: HAROptions{Hosts: []string{"vegeta.dev"}},
+			err:  ErrNoTargets,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			targets, started, err := ReadHARTargets(strings.NewReader(testHAR), tc.opts)
+			if err != tc.err {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+
+			if diff := cmp.Diff(tc.targets, targets); diff != "" {
+				t.Errorf("unexpected targets (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.started, started); diff != "" {
+				t.Errorf("unexpected start times (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHARTargetRequest(t *testing.T) {
+	t.Parallel()
+
+	targets, _, err := ReadHARTargets(strings.NewReader(testHAR), HAROptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := targets[1].Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := req.Host, "api.goku.dev:8443"; have != want {
+		t.Errorf("Host: have %q, want %q", have, want)
+	}
+
+	var buf strings.Builder
+	if err := req.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Host", "Content-Length", "Content-Type"} {
+		if n := strings.Count(strings.ToLower(buf.String()), "\r\n"+strings.ToLower(name)+":"); n != 1 {
+			t.Errorf("request has %d %s headers, want 1:\n%s", n, name, buf.String())
+		}
+	}
+
+	if strings.Contains(strings.ToLower(buf.String()), "connection:") {
+		t.Errorf("request has a hop-by-hop Connection header:\n%s", buf.String())
+	}
+}
+
+func TestHARTargeter(t *testing.T) {
+	t.Parallel()
+
+	hdr := http.Header{"accept": {"*/*"}}
+	tr := NewHARTargeter(strings.NewReader(testHAR), []byte("default"), hdr, HAROptions{
+		Hosts: []string{"goku.dev"},
+	})
+
+	want := []Target{
+		{
+			Method: "GET",
+			URL:    "https://goku.dev/",
+			Body:   []byte("default"),
+			Header: http.Header{"accept": {"*/*", "text/html"}, "Cookie": {"session=1"}, "Host": {"goku.dev"}},
+		},
+		{
+			Method: "POST",
+			URL:    "https://goku.dev/login",
+			Body:   []byte("pass=kamehameha&user=goku"),
+			Header: http.Header{"accept": {"*/*"}, "Content-Type": {"application/x-www-form-urlencoded"}},
+		},
+	}
+
+	got, err := ReadAllTargets(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected targets (-want +got):\n%s", diff)
+	}
+
+	if got, want := hdr["accept"], []string{"*/*"}; !cmp.Equal(got, want) {
+		t.Errorf("default headers were modified: got %v, want %v", got, want)
+	}
+
+	tr = NewHARTargeter(strings.NewReader("not a HAR"), nil, nil, HAROptions{})
+	if err := tr(&Target{}); err == nil || !strings.HasPrefix(err.Error(), "bad HAR") {
+		t.Errorf("got error %v, want bad HAR error", err)
+	}
+}
+
+func TestHAREncoder(t *testing.T) {
+	t.Parallel()
+
+	began := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	results := []Result{
+		{
+			Attack:    "goku",
+			Seq:       0,
+			Code:      200,
+			Timestamp: began,
+			Latency:   1500 * time.Microsecond,
+			BytesIn:   2,
+			Body:      []byte("ok"),
+			Method:    "GET",
+			URL:       "https://goku.dev/things?b=2&a=1",
+			Headers:   http.Header{"Content-Type": {"text/plain"}, "X-B": {"1", "2"}},
+		},
+		{
+			Attack:    "goku",
+			Seq:       1,
+			Code:      0,
+			Timestamp: began.Add(time.Second),
+			Error:     "connection refused",
+			Body:      []byte{0xff, 0xfe},
+			Method:    "POST",
+			URL:       "https://goku.dev/things",
+		},
+	}
+
+	var buf strings.Builder
+	enc, end := NewHAREncoder(&buf)
+	for i := range results {
+		if err := enc.Encode(&results[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := end(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatalf("invalid HAR: %v\n%s", err, buf.String())
+	}
+
+	want := []harEntry{
+		{
+			StartedDateTime: began,
+			Time:            1.5,
+			Request: harRequest{
+				Method:      "GET",
+				URL:         "https://goku.dev/things?b=2&a=1",
+				Cookies:     []harNVP{},
+				Headers:     []harNVP{},
+				QueryString: []harNVP{{"a", "1"}, {"b", "2"}},
+				HeadersSize: -1,
+			},
+			Response: harResponse{
+				Status:      200,
+				StatusText:  "OK",
+				Cookies:     []harNVP{},
+				Headers:     []harNVP{{"Content-Type", "text/plain"}, {"X-B", "1"}, {"X-B", "2"}},
+				Content:     harContent{Size: 2, MimeType: "text/plain", Text: "ok"},
+				HeadersSize: -1,
+				BodySize:    2,
+			},
+			Timings: harTimings{Wait: 1.5},
+			Attack:  "goku",
+		},
+		{
+			StartedDateTime: began.Add(time.Second),
+			Request: harRequest{
+				Method:      "POST",
+				URL:         "https://goku.dev/things",
+				Cookies:     []harNVP{},
+				Headers:     []harNVP{},
+				QueryString: []harNVP{},
+				HeadersSize: -1,
+			},
+			Response: harResponse{
+				Cookies:     []harNVP{},
+				Headers:     []harNVP{},
+				Content:     harContent{Size: 2, Text: "//4=", Encoding: "base64"},
+				HeadersSize: -1,
+			},
+			Attack: "goku",
+			Seq:    1,
+			Error:  "connection refused",
+		},
+	}
+
+	if diff := cmp.Diff(want, doc.Log.Entries); diff != "" {
+		t.Errorf("unexpected entries (-want +got):\n%s", diff)
+	}
+
+	// An empty document is still valid.
+	buf.Reset()
+	if _, end = NewHAREncoder(&buf); end() != nil {
+		t.Fatal("failed to end empty document")
+	}
+
+	if err := json.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatalf("invalid empty HAR: %v\n%s", err, buf.String())
+	} else if len(doc.Log.Entries) != 0 {
+		t.Errorf("got %d entries, want none", len(doc.Log.Entries))
+	}
+}
@@ -0,0 +1,165 @@
+package vegeta
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Default HDRHistogram parameters, which track latencies from a microsecond
+// to an hour with three significant digits.
+const (
+	DefaultHDRLowest  = time.Microsecond
+	DefaultHDRHighest = time.Hour
+	DefaultHDRDigits  = 3
+)
+
+// HDRHistogram is an HdrHistogram of latencies. Set as the HDR of
+// LatencyMetrics, it's used instead of the default t-digest to compute
+// latency quantiles, which are then exact up to the configured number of
+// significant digits. Unlike a t-digest, HDRHistograms with the same
+// parameters can be merged with no loss of precision, such as those of
+// attacks run on several machines.
+type HDRHistogram struct {
+	h *hdrhistogram.Histogram
+}
+
+// NewHDRHistogram returns a new HDRHistogram which tracks latencies between
+// lowest and highest with the given number of significant digits, from 1 to
+// 5. Latencies outside of that range are recorded as the lowest or highest.
+func NewHDRHistogram(lowest, highest time.Duration, digits int) (*HDRHistogram, error) {
+	switch {
+	case lowest < 1:
+		return nil, fmt.Errorf("bad HDR histogram lowest latency %s: must be at least 1ns", lowest)
+	case highest < 2*lowest:
+		return nil, fmt.Errorf("bad HDR histogram highest latency %s: must be at least twice the lowest", highest)
+	case digits < 1 || digits > 5:
+		return nil, fmt.Errorf("bad HDR histogram significant digits %d: must be between 1 and 5", digits)
+	}
+	return &HDRHistogram{h: hdrhistogram.New(int64(lowest), int64(highest), digits)}, nil
+}
+
+// Record records the given latency.
+func (h *HDRHistogram) Record(latency time.Duration) {
+	v := min(max(int64(latency), h.h.LowestTrackableValue()), h.h.HighestTrackableValue())
+	_ = h.h.RecordValue(v) // Can't fail, since v is in range.
+}
+
+// Quantile returns the latency at the given quantile, from 0 to 1.
+func (h *HDRHistogram) Quantile(q float64) time.Duration {
+	return time.Duration(h.h.ValueAtQuantile(q * 100))
+}
+
+// Count returns the number of recorded latencies.
+func (h *HDRHistogram) Count() int64 { return h.h.TotalCount() }
+
+// Merge adds all the latencies recorded in the given HDRHistogram to h.
+func (h *HDRHistogram) Merge(other *HDRHistogram) {
+	h.h.Merge(other.h)
+}
+
+// hdrEstimator adapts an HDRHistogram to the estimator interface.
+type hdrEstimator struct{ *HDRHistogram }
+
+func (e hdrEstimator) Add(s float64)         { e.Record(time.Duration(s)) }
+func (e hdrEstimator) Get(q float64) float64 { return float64(e.Quantile(q)) }
+
+// HDRLog is a Report which records Result latencies into an HDRHistogram
+// per consecutive time interval of an attack, to be written out in the
+// HdrHistogram interval log format by NewHDRLogReporter.
+type HDRLog struct {
+	// Interval is the duration of each interval.
+	Interval time.Duration
+
+	lowest, highest time.Duration
+	digits          int
+	intervals       map[int64]*HDRHistogram // By interval start in Unix nanoseconds.
+}
+
+// NewHDRLog returns a new HDRLog of intervals of the given duration, with
+// HDRHistograms of the given parameters as in NewHDRHistogram.
+func NewHDRLog(interval, lowest, highest time.Duration, digits int) (*HDRLog, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("bad HDR log interval %s: must be positive", interval)
+	}
+
+	// Validate the histogram parameters up front.
+	if _, err := NewHDRHistogram(lowest, highest, digits); err != nil {
+		return nil, err
+	}
+
+	return &HDRLog{
+		Interval:  interval,
+		lowest:    lowest,
+		highest:   highest,
+		digits:    digits,
+		intervals: map[int64]*HDRHistogram{},
+	}, nil
+}
+
+// Add implements the Add method of the Report interface by recording the
+// latency of the given Result in the HDRHistogram of its interval.
+func (l *HDRLog) Add(r *Result) {
+	start := r.Timestamp.Truncate(l.Interval).UnixNano()
+	h, ok := l.intervals[start]
+	if !ok {
+		h, _ = NewHDRHistogram(l.lowest, l.highest, l.digits)
+		l.intervals[start] = h
+	}
+	h.Record(r.Latency)
+}
+
+// NewHDRLogReporter returns a Reporter that writes out the HDRHistograms of
+// an HDRLog in the HdrHistogram interval log format, which can be analyzed
+// with the HdrHistogram tools, such as HistogramLogProcessor. Interval
+// timestamps are relative to the start of the first interval, which is the
+// log's StartTime and BaseTime, and interval maximums are in milliseconds.
+func NewHDRLogReporter(l *HDRLog) Reporter {
+	return func(w io.Writer) error {
+		starts := make([]int64, 0, len(l.intervals))
+		for start := range l.intervals {
+			starts = append(starts, start)
+		}
+
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+		if _, err := fmt.Fprintf(w, "#[Histogram log format version %s]\n", hdrhistogram.HISTOGRAM_LOG_FORMAT_VERSION); err != nil {
+			return err
+		}
+
+		if len(starts) > 0 {
+			base := time.Unix(0, starts[0])
+			secs := float64(starts[0]) / 1e9
+			if _, err := fmt.Fprintf(w, "#[StartTime: %.3f (seconds since epoch), %s]\n#[BaseTime: %.3f (seconds since epoch)]\n",
+				secs, base.UTC().Format(time.RFC3339), secs); err != nil {
+				return err
+			}
+		}
+
+		if _, err := io.WriteString(w, "\"StartTimestamp\",\"Interval_Length\",\"Interval_Max\",\"Interval_Compressed_Histogram\"\n"); err != nil {
+			return err
+		}
+
+		for _, start := range starts {
+			h := l.intervals[start].h
+			payload, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+			if err != nil {
+				return err
+			}
+
+			if _, err = fmt.Fprintf(w, "%.3f,%.3f,%.3f,%s\n",
+				float64(start-starts[0])/1e9,
+				l.Interval.Seconds(),
+				float64(h.Max())/float64(time.Millisecond),
+				payload,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
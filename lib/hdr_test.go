@@ -0,0 +1,140 @@
+package vegeta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+func TestNewHDRHistogram(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		lowest, highest time.Duration
+		digits          int
+		err             string
+	}{
+		{time.Microsecond, time.Hour, 3, ""},
+		{0, time.Hour, 3, "lowest latency"},
+		{time.Second, time.Second, 3, "highest latency"},
+		{time.Microsecond, time.Hour, 0, "significant digits"},
+		{time.Microsecond, time.Hour, 6, "significant digits"},
+	} {
+		_, err := NewHDRHistogram(tc.lowest, tc.highest, tc.digits)
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("NewHDRHistogram(%s, %s, %d): got error %v, want %q", tc.lowest, tc.highest, tc.digits, err, tc.err)
+		}
+	}
+}
+
+func TestHDRHistogram(t *testing.T) {
+	t.Parallel()
+
+	a, _ := NewHDRHistogram(time.Microsecond, time.Minute, 3)
+	b, _ := NewHDRHistogram(time.Microsecond, time.Minute, 3)
+	for i := 1; i <= 1000; i++ {
+		h := a
+		if i%2 == 0 {
+			h = b
+		}
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	// Out of range latencies are clamped.
+	b.Record(time.Hour)
+	b.Record(0)
+
+	a.Merge(b)
+
+	if got, want := a.Count(), int64(1002); got != want {
+		t.Fatalf("got count %d, want %d", got, want)
+	}
+
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, 500 * time.Millisecond},
+		{0.99, 991 * time.Millisecond},
+		{1, time.Minute},
+	} {
+		got := a.Quantile(tc.q)
+		if diff := (got - tc.want).Abs(); float64(diff) > 0.001*float64(tc.want) {
+			t.Errorf("quantile %v: got %s, want %s within 0.1%%", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestLatencyMetricsHDR(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Latencies.HDR, _ = NewHDRHistogram(time.Microsecond, time.Minute, 3)
+	for i := 1; i <= 1000; i++ {
+		m.Add(&Result{Code: 200, Timestamp: time.Unix(int64(i), 0), Latency: time.Duration(i) * time.Millisecond})
+	}
+	m.Close()
+
+	if got, want := m.Latencies.HDR.Count(), int64(1000); got != want {
+		t.Fatalf("HDR got %d latencies, want %d", got, want)
+	}
+
+	if got, want := m.Latencies.P99, 990*time.Millisecond; (got - want).Abs() > time.Millisecond {
+		t.Errorf("got p99 %s, want %s", got, want)
+	}
+}
+
+func TestHDRLogReporter(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewHDRLog(time.Second, time.Microsecond, time.Minute, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	began := time.Unix(1700000000, 0)
+	for i := 0; i < 300; i++ {
+		l.Add(&Result{
+			Timestamp: began.Add(time.Duration(i) * 10 * time.Millisecond),
+			Latency:   time.Duration(i%100+1) * time.Millisecond,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := NewHDRLogReporter(l).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"#[StartTime: 1700000000.000 (seconds since epoch), 2023-11-14T22:13:20Z]\n",
+		"#[BaseTime: 1700000000.000 (seconds since epoch)]\n",
+		"\n1.000,1.000,100.", // Second interval, with a 100ms max.
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	r := hdrhistogram.NewHistogramLogReader(&buf)
+	var intervals int
+	for {
+		h, err := r.NextIntervalHistogram()
+		if err != nil {
+			t.Fatal(err)
+		} else if h == nil {
+			break
+		}
+
+		intervals++
+		if got, want := h.TotalCount(), int64(100); got != want {
+			t.Errorf("interval %d: got count %d, want %d", intervals, got, want)
+		}
+	}
+
+	if intervals != 3 {
+		t.Errorf("got %d intervals, want 3", intervals)
+	}
+}
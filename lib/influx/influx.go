@@ -0,0 +1,368 @@
+// Package influx implements a sink of attack results which writes them as
+// InfluxDB line protocol points.
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// DefaultMeasurement is the default measurement of written points.
+const DefaultMeasurement = "vegeta"
+
+// Opts configures a Sink.
+type Opts struct {
+	// Measurement is the measurement of the points. Defaults to
+	// DefaultMeasurement.
+	Measurement string
+	// Tags are added to every point.
+	Tags map[string]string
+	// Every is the interval of the aggregate points written instead of a
+	// point per Result, if positive.
+	Every time.Duration
+	// URLs normalizes the URLs of the url tag, if set, so that the number of
+	// series of URLs with IDs, such as those of templated targets, is bounded.
+	URLs *vegeta.URLNormalizer
+}
+
+// Sink writes a point per observed Result, or aggregate points of every
+// time interval, in the InfluxDB line protocol. Per Result points are
+// tagged with the attack name, method, URL and status code, and have the
+// latency in nanoseconds, bytes in, bytes out and error fields.
+type Sink struct {
+	w           io.Writer
+	measurement string
+	tags        string
+	urls        *vegeta.URLNormalizer
+	windows     *vegeta.Windows
+	buf         []byte
+	err         error
+}
+
+// NewSink returns a new Sink which writes to w, flushing it after writing
+// aggregate points if it has a Flush() error method.
+func NewSink(w io.Writer, opts Opts) *Sink {
+	s := &Sink{w: w, measurement: opts.Measurement, urls: opts.URLs}
+	if s.measurement == "" {
+		s.measurement = DefaultMeasurement
+	}
+
+	keys := make([]string, 0, len(opts.Tags))
+	for k := range opts.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags []byte
+	for _, k := range keys {
+		tags = appendTag(tags, k, opts.Tags[k])
+	}
+	s.tags = string(tags)
+
+	if opts.Every > 0 {
+		s.windows = vegeta.NewWindows(opts.Every)
+	}
+
+	return s
+}
+
+// Observe writes the point of the given Result, or the aggregate points of
+// the intervals completed by it. Write errors are returned by Close.
+func (s *Sink) Observe(r *vegeta.Result) {
+	if s.err != nil {
+		return
+	}
+
+	if s.windows != nil {
+		s.windows.Add(r)
+		s.writeWindows()
+		return
+	}
+
+	url := r.URL
+	if s.urls != nil {
+		url = s.urls.Normalize(url)
+	}
+
+	b := s.buf[:0]
+	b = append(b, escape(s.measurement, ", ")...)
+	b = append(b, s.tags...)
+	b = appendTag(b, "attack", r.Attack)
+	b = appendTag(b, "method", r.Method)
+	b = appendTag(b, "url", url)
+	b = appendTag(b, "status", strconv.FormatUint(uint64(r.Code), 10))
+	b = append(b, " latency="...)
+	b = strconv.AppendInt(b, int64(r.Latency), 10)
+	b = append(b, "i,bytes_in="...)
+	b = strconv.AppendUint(b, r.BytesIn, 10)
+	b = append(b, "i,bytes_out="...)
+	b = strconv.AppendUint(b, r.BytesOut, 10)
+	b = append(b, 'i')
+	if r.Error != "" {
+		b = append(b, ",error=\""...)
+		b = append(b, escape(r.Error, `"\`)...)
+		b = append(b, '"')
+	}
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, r.Timestamp.UnixNano(), 10)
+	b = append(b, '\n')
+
+	s.buf = b
+	_, s.err = s.w.Write(b)
+}
+
+func (s *Sink) writeWindows() {
+	ws := s.windows.Complete()
+	if len(ws) == 0 {
+		return
+	}
+
+	b := s.buf[:0]
+	for _, m := range ws {
+		b = append(b, escape(s.measurement, ", ")...)
+		b = append(b, s.tags...)
+		b = fmt.Appendf(b, " requests=%di,rate=%g,throughput=%g,success=%g", m.Requests, m.Rate, m.Throughput, m.Success)
+		b = fmt.Appendf(b, ",latency_min=%di,latency_mean=%di,latency_p50=%di,latency_p90=%di,latency_p95=%di,latency_p99=%di,latency_max=%di",
+			m.Latencies.Min, m.Latencies.Mean, m.Latencies.P50, m.Latencies.P90, m.Latencies.P95, m.Latencies.P99, m.Latencies.Max)
+		b = fmt.Appendf(b, ",bytes_in_rate=%g,bytes_out_rate=%g %d\n", m.BytesIn, m.BytesOut, m.Start.UnixNano())
+	}
+
+	s.buf = b
+	if _, s.err = s.w.Write(b); s.err == nil {
+		s.err = flush(s.w)
+	}
+}
+
+// Late returns the number of Results which weren't aggregated because they
+// arrived after the points of their interval were written.
+func (s *Sink) Late() uint64 {
+	if s.windows == nil {
+		return 0
+	}
+	return s.windows.Late
+}
+
+// Close writes the aggregate points of the remaining intervals, if any,
+// and closes the underlying writer if it's an io.Closer, such as an
+// HTTPWriter, or flushes it otherwise, returning the first write error.
+func (s *Sink) Close() error {
+	if s.err == nil && s.windows != nil {
+		s.windows.Close()
+		s.writeWindows()
+	}
+
+	if c, ok := s.w.(io.Closer); ok {
+		if err := c.Close(); s.err == nil {
+			s.err = err
+		}
+	} else if s.err == nil {
+		s.err = flush(s.w)
+	}
+
+	return s.err
+}
+
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func appendTag(b []byte, k, v string) []byte {
+	if v == "" { // Empty tag values aren't allowed.
+		return b
+	}
+	b = append(b, ',')
+	b = append(b, escape(k, ",= ")...)
+	b = append(b, '=')
+	return append(b, escape(v, ",= ")...)
+}
+
+// escape escapes the given special characters in s with backslashes.
+func escape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(special, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// HTTPWriter is an io.Writer which batches written lines and sends them to
+// an InfluxDB write endpoint, such as http://localhost:8086/api/v2/write?org=o&bucket=b
+// or http://localhost:8086/write?db=d. Batches are sent in the background so
+// that writes don't wait on the endpoint, and lines written meanwhile are
+// sent in the next batch, up to MaxPending bytes of them.
+type HTTPWriter struct {
+	// URL is the URL of the write endpoint.
+	URL string
+	// Token is the API token sent in the Authorization header, if set.
+	Token string
+	// Client is the HTTP client used to write. Defaults to http.DefaultClient.
+	Client *http.Client
+	// BatchSize is the number of bytes from which a batch is sent.
+	BatchSize int
+	// FlushInterval is the duration after which a batch is sent by a Write
+	// regardless of its size.
+	FlushInterval time.Duration
+	// MaxPending is the maximum number of bytes of lines waiting to be sent,
+	// beyond which written lines are dropped, such as while the endpoint is
+	// slow. Defaults to 4 times BatchSize.
+	MaxPending int
+	// Errors is called with the error of every batch sent in the background
+	// which failed, as well as with the number of lines dropped, if set.
+	Errors func(error)
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushed time.Time
+	sending sync.WaitGroup
+	busy    bool // Whether a batch is being sent in the background.
+	dropped int  // Lines dropped since the last batch was sent.
+}
+
+// NewHTTPWriter returns a new HTTPWriter which writes to the given URL with
+// the given API token, in batches of up to 1MiB sent at least every second
+// while being written to, with up to 4MiB of lines waiting to be sent.
+func NewHTTPWriter(url, token string) *HTTPWriter {
+	return &HTTPWriter{
+		URL:           url,
+		Token:         token,
+		BatchSize:     1 << 20,
+		MaxPending:    4 << 20,
+		FlushInterval: time.Second,
+		flushed:       time.Now(),
+	}
+}
+
+// Write adds the given lines to the current batch, sending it if it's full
+// or FlushInterval elapsed since the last one was sent. The lines are dropped
+// if the batch would exceed MaxPending.
+func (w *HTTPWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	maxPending := w.MaxPending
+	if maxPending <= 0 {
+		maxPending = 4 * w.BatchSize
+	}
+
+	if w.buf.Len()+len(p) > maxPending {
+		w.dropped += bytes.Count(p, []byte("\n"))
+	} else {
+		w.buf.Write(p)
+	}
+
+	if w.buf.Len() >= w.BatchSize || time.Since(w.flushed) >= w.FlushInterval {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+// Flush sends the current batch, if any, in the background.
+func (w *HTTPWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	return nil
+}
+
+// flush sends the current batch in the background, unless it's empty or the
+// last one is still being sent, in which case it's sent later.
+func (w *HTTPWriter) flush() {
+	if w.busy || w.buf.Len() == 0 {
+		return
+	}
+
+	batch, dropped := bytes.Clone(w.buf.Bytes()), w.dropped
+	w.buf.Reset()
+	w.flushed = time.Now()
+	w.busy = true
+	w.dropped = 0
+	w.sending.Add(1)
+
+	go func() {
+		defer w.sending.Done()
+		w.reportDropped(dropped)
+		if err := w.send(batch); err != nil && w.Errors != nil {
+			w.Errors(err)
+		}
+
+		w.mu.Lock()
+		w.busy = false
+		w.mu.Unlock()
+	}()
+}
+
+// Close waits for the batch being sent in the background, if any, and then
+// sends the remaining lines, returning the error of doing so.
+func (w *HTTPWriter) Close() error {
+	w.sending.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.reportDropped(w.dropped)
+	w.dropped = 0
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	defer w.buf.Reset()
+
+	return w.send(w.buf.Bytes())
+}
+
+// reportDropped reports the given number of dropped lines to Errors, if any.
+func (w *HTTPWriter) reportDropped(n int) {
+	if n > 0 && w.Errors != nil {
+		w.Errors(fmt.Errorf("dropped %d lines exceeding the pending lines limit of %s", n, w.URL))
+	}
+}
+
+// send sends the given batch of lines to the write endpoint.
+func (w *HTTPWriter) send(batch []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.Token)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code %d while writing to %s: %s", resp.StatusCode, w.URL, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package influx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestSink(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	s := NewSink(&buf, Opts{Tags: map[string]string{"env": "ci run"}})
+
+	began := time.Unix(1700000000, 0)
+	s.Observe(&vegeta.Result{
+		Attack:    "checkout",
+		Method:    "GET",
+		URL:       "http://goku/a,b=c",
+		Code:      200,
+		Timestamp: began,
+		Latency:   5 * time.Millisecond,
+		BytesIn:   100,
+		BytesOut:  10,
+	})
+	s.Observe(&vegeta.Result{
+		Method:    "POST",
+		URL:       "http://goku/",
+		Code:      500,
+		Error:     `500 "Internal" Server Error`,
+		Timestamp: began.Add(time.Second),
+		Latency:   time.Millisecond,
+	})
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `vegeta,env=ci\ run,attack=checkout,method=GET,url=http://goku/a\,b\=c,status=200 latency=5000000i,bytes_in=100i,bytes_out=10i 1700000000000000000
+vegeta,env=ci\ run,method=POST,url=http://goku/,status=500 latency=1000000i,bytes_in=0i,bytes_out=0i,error="500 \"Internal\" Server Error" 1700000001000000000
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSinkURLs(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	s := NewSink(&buf, Opts{URLs: &vegeta.URLNormalizer{DetectIDs: true, Max: 1}})
+
+	for _, u := range []string{"http://goku/users/1", "http://goku/users/2", "http://goku/things"} {
+		s.Observe(&vegeta.Result{Method: "GET", URL: u, Code: 200, Timestamp: time.Unix(1, 0)})
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `vegeta,method=GET,url=http://goku/users/:id,status=200 latency=0i,bytes_in=0i,bytes_out=0i 1000000000
+vegeta,method=GET,url=http://goku/users/:id,status=200 latency=0i,bytes_in=0i,bytes_out=0i 1000000000
+vegeta,method=GET,url=(other),status=200 latency=0i,bytes_in=0i,bytes_out=0i 1000000000
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSinkEvery(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	s := NewSink(&buf, Opts{Measurement: "load", Every: time.Second})
+
+	began := time.Unix(1700000000, 0)
+	for i := 0; i < 30; i++ {
+		s.Observe(&vegeta.Result{
+			Code:      200,
+			Timestamp: began.Add(time.Duration(i) * 100 * time.Millisecond),
+			Latency:   time.Millisecond,
+		})
+	}
+
+	// The first interval is complete once a result a whole interval later
+	// arrives, in case of out of order results.
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Errorf("got %d points before closing, want 1:\n%s", got, buf.String())
+	}
+
+	// A result of the written interval arriving late isn't aggregated.
+	s.Observe(&vegeta.Result{Code: 200, Timestamp: began, Latency: time.Millisecond})
+	if got := s.Late(); got != 1 {
+		t.Errorf("got %d late results, want 1", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d points, want 3:\n%s", len(lines), buf.String())
+	}
+
+	want := "load requests=10i,rate=10,throughput=10,success=1,latency_min=1000000i,"
+	if !strings.HasPrefix(lines[0], want) || !strings.HasSuffix(lines[0], " 1700000000000000000") {
+		t.Errorf("got point %q, want one starting with %q", lines[0], want)
+	}
+}
+
+func TestHTTPWriter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+		auth   string
+	)
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		wait := release
+		mu.Unlock()
+
+		<-wait
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+
+		if strings.Contains(string(body), "bad") {
+			http.Error(w, "unable to parse", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	errs := make(chan error, 1)
+	w := NewHTTPWriter(srv.URL, "secret")
+	w.BatchSize = 20
+	w.FlushInterval = time.Hour
+	w.Errors = func(err error) { errs <- err }
+
+	// Writes don't wait for the full batch to be sent, and the lines written
+	// meanwhile are sent once it's done.
+	for _, line := range []string{"m v=1i 1\n", "m v=2i 2\n", "m v=3i 3\n", "m v=4i 4\n", "m v=5i 5\n", "m v=6i 6\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	got, gotAuth := bodies, auth
+	mu.Unlock()
+
+	want := []string{"m v=1i 1\nm v=2i 2\nm v=3i 3\n", "m v=4i 4\nm v=5i 5\nm v=6i 6\n"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected batches (-want +got):\n%s", diff)
+	}
+
+	if gotAuth != "Token secret" {
+		t.Errorf("got Authorization %q, want %q", gotAuth, "Token secret")
+	}
+
+	// Lines written while a batch is being sent are dropped beyond MaxPending,
+	// and their number is reported to Errors.
+	mu.Lock()
+	release = make(chan struct{})
+	mu.Unlock()
+
+	w.MaxPending = 40
+	for _, line := range []string{"m v=1i 1\n", "m v=2i 2\n", "m v=3i 3\n", "m v=4i 4\n", "m v=5i 5\n", "m v=6i 6\n", "m v=7i 7\n", "m v=8i 8\n"} {
+		w.Write([]byte(line))
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errs; !strings.Contains(err.Error(), "dropped 1 lines") {
+		t.Errorf("got error %v, want one with the number of dropped lines", err)
+	}
+
+	mu.Lock()
+	got = bodies[2:]
+	mu.Unlock()
+
+	want = []string{"m v=1i 1\nm v=2i 2\nm v=3i 3\n", "m v=4i 4\nm v=5i 5\nm v=6i 6\nm v=7i 7\n"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected batches (-want +got):\n%s", diff)
+	}
+
+	// Errors of batches sent in the background are reported to Errors, while
+	// the one of the last batch is returned by Close.
+	w.Write([]byte("bad bad bad bad bad\n"))
+	if err := <-errs; !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("got error %v, want one with the response body", err)
+	}
+
+	w.Write([]byte("bad\n"))
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("got error %v, want one with the response body", err)
+	}
+}
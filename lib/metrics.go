@@ -147,6 +147,11 @@ type LatencyMetrics struct {
 	// Min is the minimum observed request latency.
 	Min time.Duration `json:"min"`
 
+	// HDR is an optional HDRHistogram used to compute the latency quantiles
+	// instead of the default t-digest. It must be set before adding any
+	// latencies.
+	HDR *HDRHistogram `json:"-"`
+
 	estimator estimator
 }
 
@@ -169,7 +174,9 @@ func (l LatencyMetrics) Quantile(nth float64) time.Duration {
 }
 
 func (l *LatencyMetrics) init() {
-	if l.estimator == nil {
+	if l.estimator == nil && l.HDR != nil {
+		l.estimator = hdrEstimator{l.HDR}
+	} else if l.estimator == nil {
 		// This compression parameter value is the recommended value
 		// for normal uses as per http://javadox.com/com.tdunning/t-digest/3.0/com/tdunning/math/stats/TDigest.html
 		l.estimator = newTdigestEstimator(100)
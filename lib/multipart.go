@@ -0,0 +1,137 @@
+package vegeta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Part is a part of a multipart/form-data request body: either a form
+// field with a value or a file, read at request time.
+type Part struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	File        string `json:"file,omitempty" jsonschema:"description=Path of the file whose content is sent as the part's"`
+	Filename    string `json:"filename,omitempty" jsonschema:"description=Filename of the part defaulting to the base name of file"`
+	ContentType string `json:"content_type,omitempty" jsonschema:"description=Content type of the part defaulting to application/octet-stream for files"`
+}
+
+// ParsePart parses a multipart part given in the syntax of curl's -F option:
+// name=value for a field, or name=@path for a file, optionally followed by
+// ;type=<content type> and ;filename=<name>.
+func ParsePart(s string) (Part, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return Part{}, fmt.Errorf("bad multipart part: %s", s)
+	}
+
+	p := Part{Name: name, Value: value}
+	file, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return p, nil
+	}
+
+	params := strings.Split(file, ";")
+	p.Value, p.File = "", params[0]
+	for _, param := range params[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch k {
+		case "type":
+			p.ContentType = v
+		case "filename":
+			p.Filename = strings.Trim(v, `"`)
+		default:
+			return p, fmt.Errorf("bad multipart part parameter: %s", param)
+		}
+	}
+
+	if p.File == "" {
+		return p, fmt.Errorf("bad multipart part: %s", s)
+	}
+
+	return p, nil
+}
+
+// multipartBody returns the source of a multipart/form-data body with the
+// given parts and a new random boundary, along with its content type. Files
+// are streamed from disk rather than held in memory.
+func multipartBody(parts []Part) (*bodySource, string, error) {
+	var (
+		buf  bytes.Buffer
+		segs []bodySegment
+		size int64
+	)
+
+	flush := func() {
+		if buf.Len() > 0 {
+			segs = append(segs, bodySegment{data: bytes.Clone(buf.Bytes())})
+			size += int64(buf.Len())
+			buf.Reset()
+		}
+	}
+
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		h := textproto.MIMEHeader{}
+		disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(p.Name))
+
+		if p.File != "" {
+			filename := p.Filename
+			if filename == "" {
+				filename = filepath.Base(p.File)
+			}
+			disposition += fmt.Sprintf(`; filename="%s"`, escapeQuotes(filename))
+
+			if p.ContentType == "" {
+				h.Set("Content-Type", "application/octet-stream")
+			}
+		}
+
+		h.Set("Content-Disposition", disposition)
+		if p.ContentType != "" {
+			h.Set("Content-Type", p.ContentType)
+		}
+
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if p.File == "" {
+			io.WriteString(pw, p.Value)
+			continue
+		}
+
+		fi, err := os.Stat(p.File)
+		if err != nil {
+			return nil, "", fmt.Errorf("bad multipart part %q: %w", p.Name, err)
+		}
+
+		flush()
+		segs = append(segs, bodySegment{file: p.File})
+		size += fi.Size()
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	flush()
+
+	src := &bodySource{
+		size: size,
+		open: func() (io.ReadCloser, error) { return &segmentsReader{segs: segs}, nil },
+	}
+
+	return src, w.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,547 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIOptions define which operations of an OpenAPI 3 spec are read as
+// Targets and how.
+type OpenAPIOptions struct {
+	// BaseURL is prepended to the paths of operations. It defaults to the
+	// URL of the first server of the spec and is required if that URL
+	// is relative.
+	BaseURL string
+	// IncludeTags, when not empty, restricts Targets to the operations
+	// with at least one of the given tags.
+	IncludeTags []string
+	// ExcludeTags drops the operations with any of the given tags.
+	ExcludeTags []string
+	// Weights maps operations, identified by their operationId or by their
+	// method and path (e.g. "GET /things/{id}"), to the number of times
+	// their Target is repeated. Operations have a weight of 1 by default and
+	// are dropped with a weight of 0.
+	Weights map[string]int
+}
+
+// openAPIMethods are the operations of a path item in the order
+// their Targets are generated.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// maxSampleDepth bounds the nesting of schema derived samples, which
+// guards against recursive schemas.
+const maxSampleDepth = 8
+
+// ReadOpenAPITargets reads the OpenAPI 3 spec, in YAML or JSON, from the
+// given io.Reader and returns a Target for every operation selected by the
+// given options, sorted by path.
+//
+// Path parameters, required query and header parameters, optional ones with
+// examples and request bodies are filled from the examples of the spec when
+// present, and from sample values derived from their schemas otherwise.
+// JSON request bodies are preferred over other media types.
+func ReadOpenAPITargets(src io.Reader, opts OpenAPIOptions) ([]Target, error) {
+	var root map[string]any
+	if err := yaml.NewDecoder(src).Decode(&root); err != nil {
+		return nil, fmt.Errorf("bad OpenAPI spec: %w", err)
+	}
+
+	spec := openAPISpec{root: root}
+	if v, _ := root["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("bad OpenAPI spec: unsupported version %q", v)
+	}
+
+	base, err := spec.baseURL(opts.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := spec.object(root["paths"])
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	var tgts []Target
+	for _, p := range names {
+		item := spec.object(paths[p])
+		for _, m := range openAPIMethods {
+			op := spec.object(item[m])
+			if op == nil || !opts.matchTags(op) {
+				continue
+			}
+
+			method := strings.ToUpper(m)
+			weight := opts.weight(op, method+" "+p)
+			if weight <= 0 {
+				continue
+			}
+
+			tgt, err := spec.target(base, p, method, item, op)
+			if err != nil {
+				return nil, fmt.Errorf("bad OpenAPI operation %s %s: %w", method, p, err)
+			}
+
+			for i := 0; i < weight; i++ {
+				tgts = append(tgts, tgt)
+			}
+		}
+	}
+
+	if len(tgts) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	return tgts, nil
+}
+
+func (o OpenAPIOptions) matchTags(op map[string]any) bool {
+	tags, _ := op["tags"].([]any)
+	has := func(want []string) bool {
+		for _, t := range tags {
+			for _, w := range want {
+				if fmt.Sprint(t) == w {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if len(o.IncludeTags) > 0 && !has(o.IncludeTags) {
+		return false
+	}
+
+	return !has(o.ExcludeTags)
+}
+
+func (o OpenAPIOptions) weight(op map[string]any, key string) int {
+	if id, ok := op["operationId"].(string); ok {
+		if w, ok := o.Weights[id]; ok {
+			return w
+		}
+	}
+
+	if w, ok := o.Weights[key]; ok {
+		return w
+	}
+
+	return 1
+}
+
+// openAPISpec wraps a decoded spec to resolve local references.
+type openAPISpec struct {
+	root map[string]any
+}
+
+// object returns the given node as an object, following its $ref
+// to the spec's components if it has one.
+func (s openAPISpec) object(node any) map[string]any {
+	for i := 0; i < maxSampleDepth; i++ {
+		obj, ok := normalize(node).(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj
+		}
+
+		node = s.lookup(ref)
+	}
+	return nil
+}
+
+// lookup returns the node the given local JSON pointer reference points to.
+func (s openAPISpec) lookup(ref string) any {
+	ptr, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil // Only local references are supported.
+	}
+
+	var node any = s.root
+	for _, tok := range strings.Split(ptr, "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		obj, ok := normalize(node).(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = obj[tok]
+	}
+
+	return node
+}
+
+func (s openAPISpec) baseURL(base string) (string, error) {
+	if base == "" {
+		servers, _ := s.root["servers"].([]any)
+		if len(servers) > 0 {
+			server := s.object(servers[0])
+			base, _ = server["url"].(string)
+			for name, v := range s.object(server["variables"]) {
+				def := fmt.Sprint(s.object(v)["default"])
+				base = strings.ReplaceAll(base, "{"+name+"}", def)
+			}
+		}
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("bad OpenAPI base URL %q: %w", base, err)
+	} else if !u.IsAbs() {
+		return "", fmt.Errorf("OpenAPI spec has no absolute server URL %q, a base URL is required", base)
+	}
+
+	return strings.TrimSuffix(base, "/"), nil
+}
+
+func (s openAPISpec) target(base, path, method string, item, op map[string]any) (Target, error) {
+	tgt := Target{Method: method, Header: http.Header{}}
+
+	// Operation parameters override path item ones of the same name and location.
+	params := map[string]map[string]any{}
+	var keys []string
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		ps, _ := list.([]any)
+		for _, p := range ps {
+			param := s.object(p)
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			key := in + " " + name
+			if _, ok := params[key]; !ok {
+				keys = append(keys, key)
+			}
+			params[key] = param
+		}
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		param := params[key]
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+
+		v, explicit := s.example(param)
+		if !explicit {
+			v = s.sample(param["schema"], 0)
+		}
+
+		switch {
+		case in == "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(strings.Join(paramValues(v), ",")))
+		case !required && !explicit:
+		case in == "query":
+			if obj, ok := v.(map[string]any); ok {
+				for k, pv := range obj {
+					query[k] = append(query[k], paramValues(pv)...)
+				}
+			} else {
+				query[name] = append(query[name], paramValues(v)...)
+			}
+		case in == "header":
+			tgt.Header[name] = append(tgt.Header[name], strings.Join(paramValues(v), ","))
+		}
+	}
+
+	if strings.ContainsAny(path, "{}") {
+		return tgt, fmt.Errorf("unresolved path parameters in %s", path)
+	}
+
+	tgt.URL = base + path
+	if len(query) > 0 {
+		tgt.URL += "?" + query.Encode()
+	}
+
+	body := s.object(op["requestBody"])
+	if body == nil {
+		return tgt, nil
+	}
+
+	content := s.object(body["content"])
+	mt := mediaType(content)
+	if mt == "" {
+		return tgt, nil
+	}
+
+	media := s.object(content[mt])
+	v, explicit := s.example(media)
+	if !explicit {
+		v = s.sample(media["schema"], 0)
+	}
+
+	var err error
+	switch {
+	case strings.Contains(mt, "json"):
+		tgt.Body, err = json.Marshal(v)
+	case mt == "application/x-www-form-urlencoded":
+		form := url.Values{}
+		obj, _ := v.(map[string]any)
+		for k, fv := range obj {
+			form[k] = paramValues(fv)
+		}
+		tgt.Body = []byte(form.Encode())
+	default:
+		if str, ok := v.(string); ok {
+			tgt.Body = []byte(str)
+		} else {
+			tgt.Body, err = json.Marshal(v)
+		}
+	}
+
+	if err != nil {
+		return tgt, fmt.Errorf("bad request body: %w", err)
+	}
+
+	tgt.Header["Content-Type"] = []string{mt}
+	return tgt, nil
+}
+
+// mediaType returns the preferred media type of the given request body content:
+// JSON, then URL encoded forms, then the first other one. Multipart bodies
+// aren't supported.
+func mediaType(content map[string]any) string {
+	types := make([]string, 0, len(content))
+	for mt := range content {
+		if !strings.HasPrefix(mt, "multipart/") {
+			types = append(types, mt)
+		}
+	}
+
+	if len(types) == 0 {
+		return ""
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		rank := func(mt string) int {
+			switch {
+			case mt == "application/json":
+				return 0
+			case strings.Contains(mt, "json"):
+				return 1
+			case mt == "application/x-www-form-urlencoded":
+				return 2
+			default:
+				return 3
+			}
+		}
+
+		if ri, rj := rank(types[i]), rank(types[j]); ri != rj {
+			return ri < rj
+		}
+		return types[i] < types[j]
+	})
+
+	return types[0]
+}
+
+// example returns the example of the given parameter or media type object,
+// taken from its example, the first of its examples, or the example of its
+// schema, and whether it has one at all.
+func (s openAPISpec) example(obj map[string]any) (any, bool) {
+	if v, ok := obj["example"]; ok {
+		return normalize(v), true
+	}
+
+	if examples := s.object(obj["examples"]); len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if v, ok := s.object(examples[names[0]])["value"]; ok {
+			return normalize(v), true
+		}
+	}
+
+	if v, ok := s.object(obj["schema"])["example"]; ok {
+		return normalize(v), true
+	}
+
+	return nil, false
+}
+
+// sample returns a sample value valid for the given schema.
+func (s openAPISpec) sample(node any, depth int) any {
+	schema := s.object(node)
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+
+	for _, key := range []string{"example", "default", "const"} {
+		if v, ok := schema[key]; ok {
+			return normalize(v)
+		}
+	}
+
+	for _, key := range []string{"examples", "enum"} {
+		if vs, ok := schema[key].([]any); ok && len(vs) > 0 {
+			return normalize(vs[0])
+		}
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		merged := map[string]any{}
+		for _, sub := range all {
+			if obj, ok := s.sample(sub, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if subs, ok := schema[key].([]any); ok && len(subs) > 0 {
+			return s.sample(subs[0], depth+1)
+		}
+	}
+
+	typ := schemaType(schema)
+	switch typ {
+	case "object":
+		obj := map[string]any{}
+		for name, prop := range s.object(schema["properties"]) {
+			if ro, _ := s.object(prop)["readOnly"].(bool); !ro {
+				obj[name] = s.sample(prop, depth+1)
+			}
+		}
+		return obj
+	case "array":
+		if v := s.sample(schema["items"], depth+1); v != nil {
+			return []any{v}
+		}
+		return []any{}
+	case "integer", "number":
+		n := 1.0
+		if min, ok := number(schema["minimum"]); ok {
+			n = min
+			if ex, _ := schema["exclusiveMinimum"].(bool); ex {
+				n++
+			}
+		} else if max, ok := number(schema["maximum"]); ok && max < n {
+			n = max
+		}
+		if typ == "integer" {
+			return int64(n)
+		}
+		return n
+	case "boolean":
+		return true
+	case "string":
+		switch schema["format"] {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "email":
+			return "user@example.com"
+		case "uri", "url":
+			return "https://example.com"
+		case "ipv4":
+			return "127.0.0.1"
+		case "ipv6":
+			return "::1"
+		}
+		return "string"
+	}
+
+	return nil
+}
+
+// schemaType returns the type of the given schema, which may be a list
+// of types in OpenAPI 3.1, or inferred from its keywords.
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if v != "null" {
+				return fmt.Sprint(v)
+			}
+		}
+	}
+
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	} else if _, ok := schema["items"]; ok {
+		return "array"
+	}
+
+	return ""
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// paramValues returns the string values of a parameter with the given value.
+func paramValues(v any) []string {
+	switch v := v.(type) {
+	case nil:
+		return []string{""}
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case []any:
+		vs := make([]string, 0, len(v))
+		for _, e := range v {
+			vs = append(vs, paramValues(e)...)
+		}
+		return vs
+	case map[string]any:
+		bs, _ := json.Marshal(v)
+		return []string{string(bs)}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// normalize converts the maps with non-string keys that YAML may decode
+// into maps with string keys, so that values can be encoded as JSON.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		obj := make(map[string]any, len(v))
+		for k, e := range v {
+			obj[fmt.Sprint(k)] = normalize(e)
+		}
+		return obj
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
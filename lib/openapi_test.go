@@ -0,0 +1,175 @@
+package vegeta
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testOpenAPI = `
+openapi: 3.0.3
+info: {title: Dragons, version: "1"}
+servers:
+  - url: https://{env}.goku.dev/v1
+    variables:
+      env: {default: api}
+paths:
+  /things:
+    get:
+      operationId: listThings
+      tags: [things]
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, minimum: 10}}
+        - {name: tags, in: query, schema: {type: array, items: {type: string}}, example: [a, b]}
+        - {name: cursor, in: query, schema: {type: string}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string, enum: [capsule]}}
+    post:
+      operationId: createThing
+      tags: [things, writes]
+      requestBody:
+        required: true
+        content:
+          application/xml: {schema: {type: string}}
+          application/json:
+            schema: {$ref: '#/components/schemas/Thing'}
+  /things/{id}:
+    parameters:
+      - {$ref: '#/components/parameters/ID'}
+    put:
+      tags: [things, writes]
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            examples:
+              ball: {value: {name: ball, stars: 4}}
+    delete:
+      tags: [things, writes]
+  /health:
+    get: {}
+components:
+  parameters:
+    ID: {name: id, in: path, required: true, schema: {type: string, format: uuid}}
+  schemas:
+    Thing:
+      type: object
+      properties:
+        id: {type: string, readOnly: true}
+        name: {type: string, example: ball}
+        stars: {type: integer, maximum: 7, minimum: 1}
+        created: {type: string, format: date-time}
+        owner: {$ref: '#/components/schemas/Thing'}
+        labels: {type: array, items: {type: string}}
+`
+
+func TestReadOpenAPITargets(t *testing.T) {
+	t.Parallel()
+
+	health := Target{Method: "GET", URL: "https://api.goku.dev/v1/health", Header: http.Header{}}
+	list := Target{
+		Method: "GET",
+		URL:    "https://api.goku.dev/v1/things?limit=10&tags=a&tags=b",
+		Header: http.Header{"X-Tenant": {"capsule"}},
+	}
+	create := Target{
+		Method: "POST",
+		URL:    "https://api.goku.dev/v1/things",
+		Header: http.Header{"Content-Type": {"application/json"}},
+	}
+	update := Target{
+		Method: "PUT",
+		URL:    "https://api.goku.dev/v1/things/00000000-0000-0000-0000-000000000000",
+		Body:   []byte("name=ball&stars=4"),
+		Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+	}
+	remove := Target{
+		Method: "DELETE",
+		URL:    "https://api.goku.dev/v1/things/00000000-0000-0000-0000-000000000000",
+		Header: http.Header{},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opts    OpenAPIOptions
+		targets []Target
+		err     string
+	}{
+		{
+			name:    "all",
+			targets: []Target{health, list, create, update, remove},
+		},
+		{
+			name: "tags and weights",
+			opts: OpenAPIOptions{
+				BaseURL:     "http://localhost:8080/",
+				IncludeTags: []string{"things"},
+				ExcludeTags: []string{"writes"},
+				Weights:     map[string]int{"listThings": 2},
+			},
+			targets: []Target{
+				{Method: "GET", URL: "http://localhost:8080/things?limit=10&tags=a&tags=b", Header: list.Header},
+				{Method: "GET", URL: "http://localhost:8080/things?limit=10&tags=a&tags=b", Header: list.Header},
+			},
+		},
+		{
+			name:    "weights by method and path",
+			opts:    OpenAPIOptions{Weights: map[string]int{"GET /health": 0, "listThings": 0, "createThing": 0, "PUT /things/{id}": 0}},
+			targets: []Target{remove},
+		},
+		{
+			name: "no matches",
+			opts: OpenAPIOptions{IncludeTags: []string{"dragons"}},
+			err:  ErrNoTargets.Error(),
+		},
+		{
+			name: "relative base URL",
+			opts: OpenAPIOptions{BaseURL: "/v1"},
+			err:  "a base URL is required",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			targets, err := ReadOpenAPITargets(strings.NewReader(testOpenAPI), tc.opts)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// Bodies derived from schemas are checked below.
+			for i := range targets {
+				if targets[i].Method == "POST" {
+					targets[i].Body = nil
+				}
+			}
+
+			if diff := cmp.Diff(tc.targets, targets); diff != "" {
+				t.Errorf("unexpected targets (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	targets, err := ReadOpenAPITargets(strings.NewReader(testOpenAPI), OpenAPIOptions{
+		IncludeTags: []string{"writes"},
+		Weights:     map[string]int{"PUT /things/{id}": 0, "DELETE /things/{id}": 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"created":"2024-01-01T00:00:00Z","labels":["string"],"name":"ball",` +
+		`"owner":{"created":"2024-01-01T00:00:00Z","labels":["string"],"name":"ball",`
+	if len(targets) != 1 || !strings.HasPrefix(string(targets[0].Body), want) {
+		t.Errorf("got targets %+v, want one with a body starting with %s", targets, want)
+	}
+
+	if _, err := ReadOpenAPITargets(strings.NewReader("swagger: '2.0'"), OpenAPIOptions{}); err == nil {
+		t.Error("want error with Swagger 2.0 spec")
+	}
+}
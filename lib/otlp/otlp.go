@@ -0,0 +1,345 @@
+// Package otlp implements a sink of attack results which exports them as
+// OpenTelemetry client spans in the OTLP/JSON encoding.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// DefaultServiceName is the default service.name resource attribute of
+// exported spans.
+const DefaultServiceName = "vegeta"
+
+// scopeName is the name of the instrumentation scope of exported spans.
+const scopeName = "github.com/tsenart/vegeta"
+
+// Opts configures an Exporter.
+type Opts struct {
+	// ServiceName is the service.name resource attribute of the spans.
+	// Defaults to DefaultServiceName.
+	ServiceName string
+	// BatchSize is the number of spans from which a batch is written.
+	// Defaults to 512.
+	BatchSize int
+	// FlushInterval is the duration after which a batch is written by an
+	// Observe regardless of its size. Defaults to a second.
+	FlushInterval time.Duration
+	// MaxPending is the maximum number of spans waiting to be written,
+	// beyond which observed ones are dropped, such as while w is slow.
+	// Defaults to 4 times BatchSize.
+	MaxPending int
+	// Errors is called with the error of every batch written in the
+	// background which failed, as well as with the number of spans dropped,
+	// if set.
+	Errors func(error)
+}
+
+// Exporter exports a client span per observed Result with a TraceID, as
+// set by the vegeta.TraceContext option, so that it's the parent of the
+// server spans of its request. Spans are written in batches, each as an
+// OTLP/JSON ExportTraceServiceRequest followed by a newline, which is the
+// format of the OpenTelemetry Collector's file exporter and receiver.
+// Batches are written in the background so that observing Results doesn't
+// wait on w, and spans observed meanwhile are written in the next batch,
+// up to MaxPending of them. It's safe for concurrent use.
+type Exporter struct {
+	w        io.Writer
+	opts     Opts
+	resource resource
+
+	mu      sync.Mutex
+	spans   []span
+	flushed time.Time
+	busy    bool // Whether a batch is being written in the background.
+	dropped int  // Spans dropped since the last batch was written.
+	writing sync.WaitGroup
+}
+
+// NewExporter returns a new Exporter which writes to w, flushing it after
+// every batch if it has a Flush() error method.
+func NewExporter(w io.Writer, opts Opts) *Exporter {
+	if opts.ServiceName == "" {
+		opts.ServiceName = DefaultServiceName
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 4 * opts.BatchSize
+	}
+
+	return &Exporter{
+		w:        w,
+		opts:     opts,
+		resource: resource{Attributes: []attribute{stringAttr("service.name", opts.ServiceName)}},
+		flushed:  time.Now(),
+	}
+}
+
+// Observe adds the span of the given Result to the current batch, writing
+// it in the background if it's full or FlushInterval elapsed since the last
+// one was written, unless the last one is still being written. The span is
+// dropped if MaxPending spans are already waiting to be written.
+func (e *Exporter) Observe(r *vegeta.Result) {
+	if r.TraceID == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.spans) >= e.opts.MaxPending {
+		e.dropped++
+	} else {
+		e.spans = append(e.spans, newSpan(r))
+	}
+
+	if e.busy || len(e.spans) < e.opts.BatchSize && time.Since(e.flushed) < e.opts.FlushInterval {
+		return
+	}
+
+	spans, dropped := e.spans, e.dropped
+	e.spans = make([]span, 0, len(spans))
+	e.flushed = time.Now()
+	e.busy = true
+	e.dropped = 0
+	e.writing.Add(1)
+
+	go func() {
+		defer e.writing.Done()
+		e.reportDropped(dropped)
+		if err := e.write(spans); err != nil && e.opts.Errors != nil {
+			e.opts.Errors(err)
+		}
+
+		e.mu.Lock()
+		e.busy = false
+		e.mu.Unlock()
+	}()
+}
+
+// Close waits for the batch being written in the background, if any, and
+// then writes the current batch, if any, returning the error of doing so.
+func (e *Exporter) Close() error {
+	e.writing.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.reportDropped(e.dropped)
+	e.dropped = 0
+
+	spans := e.spans
+	e.spans = nil
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return e.write(spans)
+}
+
+// reportDropped reports the given number of dropped spans to Errors, if any.
+func (e *Exporter) reportDropped(n int) {
+	if n > 0 && e.opts.Errors != nil {
+		e.opts.Errors(fmt.Errorf("dropped %d spans exceeding the limit of %d pending ones", n, e.opts.MaxPending))
+	}
+}
+
+// write writes the given batch of spans, flushing w if it has a Flush()
+// error method.
+func (e *Exporter) write(spans []span) error {
+	data, err := json.Marshal(exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = e.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if f, ok := e.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// The following types are the subset of the OTLP/JSON encoding of an
+// ExportTraceServiceRequest used by the Exporter. Trace and span IDs are
+// hex encoded and 64 bit integers are encoded as strings.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	resource struct {
+		Attributes []attribute `json:"attributes"`
+	}
+
+	scopeSpans struct {
+		Scope scope  `json:"scope"`
+		Spans []span `json:"spans"`
+	}
+
+	scope struct {
+		Name string `json:"name"`
+	}
+
+	span struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		Name              string      `json:"name"`
+		Kind              int         `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []attribute `json:"attributes"`
+		Status            status      `json:"status"`
+	}
+
+	status struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	attribute struct {
+		Key   string `json:"key"`
+		Value value  `json:"value"`
+	}
+
+	value struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+// Span kinds and status codes of the OTLP trace protocol.
+const (
+	spanKindClient  = 3
+	statusCodeError = 2
+)
+
+// newSpan returns the client span of the given Result, named after its
+// method and with the attributes of the OpenTelemetry HTTP client semantic
+// conventions, as well as its attack name and sequence number.
+func newSpan(r *vegeta.Result) span {
+	s := span{
+		TraceID:           r.TraceID,
+		SpanID:            r.SpanID,
+		Name:              r.Method,
+		Kind:              spanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(r.Timestamp.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(r.End().UnixNano(), 10),
+		Attributes: []attribute{
+			stringAttr("http.request.method", r.Method),
+			stringAttr("url.full", r.URL),
+		},
+	}
+
+	if r.Code != 0 {
+		s.Attributes = append(s.Attributes, intAttr("http.response.status_code", int64(r.Code)))
+	}
+
+	s.Attributes = append(s.Attributes,
+		intAttr("http.request.body.size", int64(r.BytesOut)),
+		intAttr("http.response.body.size", int64(r.BytesIn)),
+	)
+
+	if r.Attack != "" {
+		s.Attributes = append(s.Attributes, stringAttr("vegeta.attack", r.Attack))
+	}
+
+	s.Attributes = append(s.Attributes, intAttr("vegeta.seq", int64(r.Seq)))
+
+	if r.Error != "" {
+		s.Status = status{Code: statusCodeError, Message: r.Error}
+	}
+
+	return s
+}
+
+func stringAttr(key, v string) attribute {
+	return attribute{Key: key, Value: value{StringValue: &v}}
+}
+
+func intAttr(key string, v int64) attribute {
+	s := strconv.FormatInt(v, 10)
+	return attribute{Key: key, Value: value{IntValue: &s}}
+}
+
+// HTTPWriter is an io.Writer which sends every write, expected to be an
+// OTLP/JSON ExportTraceServiceRequest as written by an Exporter, to an
+// OTLP/HTTP traces endpoint, such as http://localhost:4318/v1/traces.
+type HTTPWriter struct {
+	// URL is the URL of the traces endpoint.
+	URL string
+	// Header is added to every request, such as for authentication.
+	Header http.Header
+	// Client is the HTTP client used to send. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// NewHTTPWriter returns a new HTTPWriter which sends to the given URL with
+// the given request headers.
+func NewHTTPWriter(url string, header http.Header) *HTTPWriter {
+	return &HTTPWriter{URL: url, Header: header}
+}
+
+// Write sends p in a single request.
+func (w *HTTPWriter) Write(p []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(p))
+	if err != nil {
+		return 0, err
+	}
+
+	for k, vs := range w.Header {
+		req.Header[k] = append(req.Header[k], vs...)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("unexpected status code %d while exporting to %s: %s", resp.StatusCode, w.URL, body)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,202 @@
+package otlp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	e := NewExporter(&buf, Opts{BatchSize: 2, FlushInterval: time.Hour})
+
+	began := time.Unix(1700000000, 0)
+	e.Observe(&vegeta.Result{
+		Attack:    "checkout",
+		Seq:       1,
+		Method:    "GET",
+		URL:       "http://goku/",
+		Code:      200,
+		Timestamp: began,
+		Latency:   5 * time.Millisecond,
+		BytesIn:   100,
+		TraceID:   "0af7651916cd43dd8448eb211c80319c",
+		SpanID:    "b7ad6b7169203331",
+	})
+	e.Observe(&vegeta.Result{Method: "GET", URL: "http://goku/untraced"})
+	e.Observe(&vegeta.Result{
+		Seq:       2,
+		Method:    "POST",
+		URL:       "http://goku/",
+		Error:     "connection refused",
+		Timestamp: began.Add(time.Second),
+		Latency:   time.Millisecond,
+		BytesOut:  10,
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:    "00f067aa0ba902b7",
+	})
+	e.Observe(&vegeta.Result{
+		Seq:       3,
+		Method:    "GET",
+		URL:       "http://goku/",
+		Code:      200,
+		Timestamp: began.Add(2 * time.Second),
+		TraceID:   "00000000000000010000000000000001",
+		SpanID:    "0000000000000001",
+	})
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d batches, want 2:\n%s", len(lines), buf.String())
+	}
+
+	const prefix = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"vegeta"}}]},"scopeSpans":[{"scope":{"name":"github.com/tsenart/vegeta"},"spans":[`
+	want := prefix +
+		`{"traceId":"0af7651916cd43dd8448eb211c80319c","spanId":"b7ad6b7169203331","name":"GET","kind":3,"startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000005000000","attributes":[` +
+		`{"key":"http.request.method","value":{"stringValue":"GET"}},{"key":"url.full","value":{"stringValue":"http://goku/"}},` +
+		`{"key":"http.response.status_code","value":{"intValue":"200"}},{"key":"http.request.body.size","value":{"intValue":"0"}},` +
+		`{"key":"http.response.body.size","value":{"intValue":"100"}},{"key":"vegeta.attack","value":{"stringValue":"checkout"}},` +
+		`{"key":"vegeta.seq","value":{"intValue":"1"}}],"status":{}},` +
+		`{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7","name":"POST","kind":3,"startTimeUnixNano":"1700000001000000000","endTimeUnixNano":"1700000001001000000","attributes":[` +
+		`{"key":"http.request.method","value":{"stringValue":"POST"}},{"key":"url.full","value":{"stringValue":"http://goku/"}},` +
+		`{"key":"http.request.body.size","value":{"intValue":"10"}},{"key":"http.response.body.size","value":{"intValue":"0"}},` +
+		`{"key":"vegeta.seq","value":{"intValue":"2"}}],"status":{"code":2,"message":"connection refused"}}` +
+		`]}]}]}`
+
+	if got := lines[0]; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if !strings.HasPrefix(lines[1], prefix+`{"traceId":"00000000000000010000000000000001","spanId":"0000000000000001"`) {
+		t.Errorf("bad last batch: %s", lines[1])
+	}
+}
+
+// flakyWriter fails its first write.
+type flakyWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.writes++; w.writes == 1 {
+		return 0, errors.New("unavailable")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestExporterErrors(t *testing.T) {
+	t.Parallel()
+
+	errs := make(chan error, 1)
+	var w flakyWriter
+	e := NewExporter(&w, Opts{BatchSize: 1, Errors: func(err error) { errs <- err }})
+
+	// The error of a batch written in the background is reported to Errors,
+	// and doesn't keep the following batches from being written.
+	e.Observe(&vegeta.Result{Method: "GET", TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+	if err := <-errs; err == nil || err.Error() != "unavailable" {
+		t.Errorf("got error %v, want unavailable", err)
+	}
+
+	e.Observe(&vegeta.Result{Method: "GET", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := w.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"spanId":"00f067aa0ba902b7"`) {
+		t.Errorf("got batches %s, want the second span's", got)
+	}
+}
+
+// blockingWriter blocks writes until release is closed.
+type blockingWriter struct {
+	bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.Buffer.Write(p)
+}
+
+func TestExporterMaxPending(t *testing.T) {
+	t.Parallel()
+
+	errs := make(chan error, 1)
+	w := blockingWriter{release: make(chan struct{})}
+	e := NewExporter(&w, Opts{BatchSize: 2, MaxPending: 3, FlushInterval: time.Hour, Errors: func(err error) { errs <- err }})
+
+	// Spans observed while a batch is being written are dropped beyond
+	// MaxPending, and their number is reported to Errors.
+	for i := 0; i < 6; i++ {
+		e.Observe(&vegeta.Result{Method: "GET", TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+	}
+
+	close(w.release)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "dropped 1 spans") {
+		t.Errorf("got error %v, want one with the number of dropped spans", err)
+	}
+
+	if got := strings.Count(w.String(), `"spanId"`); got != 5 {
+		t.Errorf("got %d written spans, want 5", got)
+	}
+}
+
+func TestHTTPWriter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		bodies []string
+		auth   string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		auth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	w := NewHTTPWriter(srv.URL+"/v1/traces", http.Header{"Authorization": {"Bearer secret"}})
+	e := NewExporter(w, Opts{})
+	e.Observe(&vegeta.Result{Method: "GET", URL: "http://goku/", TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"traceId":"0af7651916cd43dd8448eb211c80319c"`) {
+		t.Errorf("bad bodies: %q", bodies)
+	}
+
+	if have, want := auth, "Bearer secret"; have != want {
+		t.Errorf("Authorization: have %q, want %q", have, want)
+	}
+
+	w.URL = srv.URL + "/bad"
+	if _, err := w.Write([]byte("{}")); err == nil || !strings.Contains(err.Error(), "unexpected status code 400") {
+		t.Errorf("got error %v, want unexpected status code", err)
+	}
+}
@@ -3,6 +3,7 @@ package vegeta
 import (
 	"fmt"
 	"math"
+	"sort"
 	"time"
 )
 
@@ -307,3 +308,55 @@ func (p LinearPacer) hits(t time.Duration) float64 {
 
 	return (a*math.Pow(x, 2))/2 + b*x
 }
+
+// ReplayPacer paces an attack by replaying a recorded schedule of hits, each
+// being sent at its offset from the beginning of the attack. The offsets must
+// be sorted in ascending order. The attack stops after the last one is sent.
+type ReplayPacer []time.Duration
+
+// NewReplayPacer returns a ReplayPacer that replays hits at the given
+// timestamps, relative to the earliest one, with the offsets between them
+// divided by the given speed. A speed of 2 replays the schedule twice as fast.
+func NewReplayPacer(timestamps []time.Time, speed float64) ReplayPacer {
+	if len(timestamps) == 0 {
+		return ReplayPacer{}
+	}
+
+	ts := make([]time.Time, len(timestamps))
+	copy(ts, timestamps)
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+
+	if speed <= 0 {
+		speed = 1
+	}
+
+	p := make(ReplayPacer, len(ts))
+	for i := range ts {
+		p[i] = time.Duration(float64(ts[i].Sub(ts[0])) / speed)
+	}
+
+	return p
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p ReplayPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	if hits >= uint64(len(p)) {
+		return 0, true
+	}
+
+	if wait := p[hits] - elapsed; wait > 0 {
+		return wait, false
+	}
+
+	// Running behind, send next hit immediately.
+	return 0, false
+}
+
+// Rate returns a ReplayPacer's instantaneous hit rate (i.e. requests per second)
+// at the given elapsed duration of an attack, measured as the number of hits
+// scheduled in the second that follows it.
+func (p ReplayPacer) Rate(elapsed time.Duration) float64 {
+	from := sort.Search(len(p), func(i int) bool { return p[i] >= elapsed })
+	to := sort.Search(len(p), func(i int) bool { return p[i] >= elapsed+time.Second })
+	return float64(to - from)
+}
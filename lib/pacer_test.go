@@ -2,6 +2,7 @@ package vegeta
 
 import (
 	"math"
+	"reflect"
 	"strconv"
 	"testing"
 	"testing/quick"
@@ -426,3 +427,44 @@ func TestLinearPacer_Rate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReplayPacer(t *testing.T) {
+	t.Parallel()
+
+	began := time.Unix(1000, 0)
+	p := NewReplayPacer([]time.Time{
+		began.Add(2 * time.Second),
+		began,
+		began.Add(500 * time.Millisecond),
+		began.Add(time.Second),
+	}, 2)
+
+	want := ReplayPacer{0, 250 * time.Millisecond, 500 * time.Millisecond, time.Second}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("got offsets %v, want %v", p, want)
+	}
+
+	for ti, tt := range []struct {
+		elapsed time.Duration
+		hits    uint64
+		wait    time.Duration
+		stop    bool
+	}{
+		{0, 0, 0, false},
+		{0, 1, 250 * time.Millisecond, false},
+		{100 * time.Millisecond, 2, 400 * time.Millisecond, false},
+		{time.Second, 2, 0, false}, // Running behind, no wait
+		{time.Second, 3, 0, false},
+		{time.Second, 4, 0, true},
+	} {
+		wait, stop := p.Pace(tt.elapsed, tt.hits)
+		if wait != tt.wait || stop != tt.stop {
+			t.Errorf("%d: %v.Pace(%s, %d) = (%s, %t); want (%s, %t)",
+				ti, p, tt.elapsed, tt.hits, wait, stop, tt.wait, tt.stop)
+		}
+	}
+
+	if got, want := p.Rate(0), 3.0; got != want {
+		t.Errorf("got rate %v, want %v", got, want)
+	}
+}
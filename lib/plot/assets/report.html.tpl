@@ -0,0 +1,206 @@
+<!doctype html>
+<html>
+<head>
+  <title>{{.Title}}</title>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <style>{{.UPlotCSS}}</style>
+  <style>
+    * {
+      box-sizing: border-box;
+    }
+
+    body {
+      font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', 'Roboto', 'Oxygen', 'Ubuntu', 'Cantarell', sans-serif;
+      margin: 0;
+      padding: 20px;
+      background: #0f1419;
+      color: #e6edf3;
+    }
+
+    .container {
+      max-width: 1200px;
+      margin: 0 auto;
+    }
+
+    h1 {
+      font-size: 24px;
+      font-weight: 600;
+      margin: 0 0 20px;
+    }
+
+    h2 {
+      font-size: 16px;
+      font-weight: 600;
+      margin: 0 0 12px;
+    }
+
+    .grid {
+      display: grid;
+      grid-template-columns: repeat(auto-fit, minmax(260px, 1fr));
+      gap: 20px;
+      margin-bottom: 20px;
+    }
+
+    .card {
+      background: #161b22;
+      border: 1px solid #30363d;
+      border-radius: 8px;
+      padding: 20px;
+      margin-bottom: 20px;
+    }
+
+    .grid .card {
+      margin-bottom: 0;
+    }
+
+    table {
+      width: 100%;
+      border-collapse: collapse;
+      font-size: 13px;
+    }
+
+    th, td {
+      padding: 6px 8px;
+      border-bottom: 1px solid #30363d;
+      text-align: left;
+    }
+
+    td.num, th.num {
+      text-align: right;
+      font-family: 'SF Mono', 'Monaco', 'Menlo', 'Consolas', 'Liberation Mono', monospace;
+    }
+
+    th {
+      color: #8b949e;
+      font-weight: 500;
+    }
+
+    ul.errors {
+      margin: 0;
+      padding-left: 20px;
+      font-family: 'SF Mono', 'Monaco', 'Menlo', 'Consolas', 'Liberation Mono', monospace;
+      font-size: 12px;
+    }
+
+    .uplot .u-axis {
+      color: #8b949e;
+    }
+
+    .uplot .u-legend {
+      font-size: 13px;
+    }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <h1>{{.Title}}</h1>
+
+    <div class="grid">
+      <div class="card">
+        <h2>Summary</h2>
+        <table>
+          {{- range .Summary}}
+          <tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+          {{- end}}
+        </table>
+      </div>
+
+      <div class="card">
+        <h2>Latencies</h2>
+        <table>
+          {{- range .Latencies}}
+          <tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+          {{- end}}
+        </table>
+      </div>
+
+      <div class="card">
+        <h2>Bytes</h2>
+        <table>
+          {{- range .Bytes}}
+          <tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+          {{- end}}
+        </table>
+      </div>
+    </div>
+
+    <div class="card">
+      <h2>Latency Histogram</h2>
+      <div id="histogram"></div>
+    </div>
+
+    <div class="grid">
+      <div class="card">
+        <h2>Status Codes</h2>
+        <table>
+          <tr><th>Code</th><th class="num">Count</th><th class="num">%</th></tr>
+          {{- range .StatusCodes}}
+          <tr><td>{{.Code}}</td><td class="num">{{.Count}}</td><td class="num">{{printf "%.2f" .Percent}}</td></tr>
+          {{- end}}
+        </table>
+      </div>
+
+      <div class="card">
+        <h2>Errors</h2>
+        {{- if .Errors}}
+        <ul class="errors">
+          {{- range .Errors}}
+          <li>{{.}}</li>
+          {{- end}}
+        </ul>
+        {{- else}}
+        <p>None</p>
+        {{- end}}
+      </div>
+    </div>
+  </div>
+
+  <script>{{.UPlotJS}}</script>
+  <script>{{.PluginsJS}}</script>
+  <script>
+    (function() {
+      const hist = {{.Histogram}};
+      const el = document.getElementById('histogram');
+      if (!hist.counts || hist.counts.length === 0) {
+        el.textContent = 'No results';
+        return;
+      }
+
+      const xs = hist.counts.map((_, i) => i);
+      const bin = i => formatDuration(hist.lower[i]).trim() + ' – ' + formatDuration(hist.upper[i]).trim();
+
+      const opts = {
+        width: Math.max(600, el.parentElement.clientWidth - 40),
+        height: 400,
+        scales: { x: { time: false } },
+        series: [
+          { label: 'Latency', value: (u, i) => i == null ? '-' : bin(i) },
+          {
+            label: 'Requests',
+            stroke: '#1f6feb',
+            fill: 'rgba(31, 111, 235, 0.5)',
+            paths: uPlot.paths.bars({ size: [0.9, 100] }),
+            points: { show: false },
+            value: (u, v) => v == null ? '-' : Math.round(v)
+          }
+        ],
+        axes: [
+          {
+            stroke: '#8b949e',
+            grid: { stroke: '#30363d', width: 1 },
+            values: (u, vals) => vals.map(i => Number.isInteger(i) && i < hist.lower.length ? formatDuration(hist.lower[i]).trim() : '')
+          },
+          {
+            stroke: '#8b949e',
+            grid: { stroke: '#30363d', width: 1 },
+            size: 60
+          }
+        ]
+      };
+
+      new uPlot(opts, [xs, hist.counts], el);
+    })();
+  </script>
+</body>
+</html>
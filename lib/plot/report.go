@@ -0,0 +1,186 @@
+package plot
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// histogramBins is the number of bins of the latency histogram of an HTML
+// report of Metrics without a bucketed Histogram.
+const histogramBins = 30
+
+// NewHTMLReporter returns a vegeta.Reporter that writes out Metrics as a
+// self-contained HTML page with the summary, latency, bytes, status code and
+// error tables of the text report, and a latency histogram. The histogram
+// uses the buckets of the Metrics Histogram if set, or else it's estimated
+// from the latency quantiles in evenly spaced bins between the minimum and
+// maximum latencies.
+func NewHTMLReporter(m *vegeta.Metrics, title string) vegeta.Reporter {
+	type row struct {
+		Name  string
+		Value string
+	}
+
+	type statusCode struct {
+		Code    string
+		Count   int
+		Percent float64
+	}
+
+	type reportData struct {
+		Title       string
+		UPlotCSS    template.CSS
+		UPlotJS     template.JS
+		PluginsJS   template.JS
+		Summary     []row
+		Latencies   []row
+		Bytes       []row
+		StatusCodes []statusCode
+		Errors      []string
+		Histogram   template.JS
+	}
+
+	return func(w io.Writer) error {
+		assets := map[string][]byte{}
+		for _, path := range []string{"uPlot.min.js", "uPlot.min.css", "uplot-plugins.js"} {
+			bs, err := asset(path)
+			if err != nil {
+				return err
+			}
+			assets[path] = bs
+		}
+
+		hist, err := json.Marshal(histogram(m))
+		if err != nil {
+			return err
+		}
+
+		data := reportData{
+			Title:     title,
+			UPlotCSS:  template.CSS(assets["uPlot.min.css"]),
+			UPlotJS:   template.JS(assets["uPlot.min.js"]),
+			PluginsJS: template.JS(assets["uplot-plugins.js"]),
+			Summary: []row{
+				{"Requests", strconv.FormatUint(m.Requests, 10)},
+				{"Rate", fmt.Sprintf("%.2f", m.Rate) + "/s"},
+				{"Throughput", fmt.Sprintf("%.2f", m.Throughput) + "/s"},
+				{"Duration", vegeta.RoundDuration(m.Duration + m.Wait).String()},
+				{"Attack", vegeta.RoundDuration(m.Duration).String()},
+				{"Wait", vegeta.RoundDuration(m.Wait).String()},
+				{"Success", fmt.Sprintf("%.2f", m.Success*100) + "%"},
+			},
+			Latencies: []row{
+				{"Min", vegeta.RoundDuration(m.Latencies.Min).String()},
+				{"Mean", vegeta.RoundDuration(m.Latencies.Mean).String()},
+				{"50", vegeta.RoundDuration(m.Latencies.P50).String()},
+				{"90", vegeta.RoundDuration(m.Latencies.P90).String()},
+				{"95", vegeta.RoundDuration(m.Latencies.P95).String()},
+				{"99", vegeta.RoundDuration(m.Latencies.P99).String()},
+				{"Max", vegeta.RoundDuration(m.Latencies.Max).String()},
+			},
+			Bytes: []row{
+				{"In total", strconv.FormatUint(m.BytesIn.Total, 10)},
+				{"In mean", fmt.Sprintf("%.2f", m.BytesIn.Mean)},
+				{"Out total", strconv.FormatUint(m.BytesOut.Total, 10)},
+				{"Out mean", fmt.Sprintf("%.2f", m.BytesOut.Mean)},
+			},
+			Errors:    m.Errors,
+			Histogram: template.JS(hist),
+		}
+
+		for code, count := range m.StatusCodes {
+			pct := 0.0
+			if m.Requests > 0 {
+				pct = 100 * float64(count) / float64(m.Requests)
+			}
+			data.StatusCodes = append(data.StatusCodes, statusCode{code, count, pct})
+		}
+
+		sort.Slice(data.StatusCodes, func(i, j int) bool {
+			return data.StatusCodes[i].Code < data.StatusCodes[j].Code
+		})
+
+		return reportTemplate.Execute(w, &data)
+	}
+}
+
+// histogramData is the latency histogram of an HTML report, with the bins'
+// lower and upper bounds in milliseconds and their counts.
+type histogramData struct {
+	Lower  []float64 `json:"lower"`
+	Upper  []float64 `json:"upper"`
+	Counts []float64 `json:"counts"`
+}
+
+func histogram(m *vegeta.Metrics) histogramData {
+	var h histogramData
+	if m.Requests == 0 {
+		return h
+	}
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+	if m.Histogram != nil && len(m.Histogram.Buckets) > 0 {
+		bs := m.Histogram.Buckets
+		for i := range bs {
+			upper := m.Latencies.Max
+			if i < len(bs)-1 {
+				upper = bs[i+1]
+			}
+
+			var count uint64
+			if i < len(m.Histogram.Counts) {
+				count = m.Histogram.Counts[i]
+			}
+
+			h.Lower = append(h.Lower, ms(bs[i]))
+			h.Upper = append(h.Upper, ms(max(upper, bs[i])))
+			h.Counts = append(h.Counts, float64(count))
+		}
+		return h
+	}
+
+	lo, hi := m.Latencies.Min, m.Latencies.Max
+	width := (hi - lo) / histogramBins
+	if width <= 0 {
+		return histogramData{
+			Lower:  []float64{ms(lo)},
+			Upper:  []float64{ms(hi)},
+			Counts: []float64{float64(m.Requests)},
+		}
+	}
+
+	h.Counts = make([]float64, histogramBins)
+	for i := 0; i < histogramBins; i++ {
+		h.Lower = append(h.Lower, ms(lo+time.Duration(i)*width))
+		h.Upper = append(h.Upper, ms(lo+time.Duration(i+1)*width))
+	}
+	h.Upper[histogramBins-1] = ms(hi)
+
+	// The latency distribution is sampled at evenly spaced quantiles,
+	// each standing for an equal share of all requests.
+	const samples = 1000
+	weight := float64(m.Requests) / samples
+	for i := 0; i < samples; i++ {
+		q := (float64(i) + 0.5) / samples
+		bin := int((m.Latencies.Quantile(q) - lo) / width)
+		h.Counts[min(max(bin, 0), histogramBins-1)] += weight
+	}
+
+	return h
+}
+
+var reportTemplate = func() *template.Template {
+	bs, err := asset("report.html.tpl")
+	if err != nil {
+		panic(err)
+	}
+	return template.Must(template.New("report").Parse(string(bs)))
+}()
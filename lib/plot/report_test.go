@@ -0,0 +1,83 @@
+package plot
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestHTMLReporter(t *testing.T) {
+	t.Parallel()
+
+	var m vegeta.Metrics
+	for i := 0; i < 100; i++ {
+		r := vegeta.Result{
+			Code:      200,
+			Timestamp: time.Unix(int64(i), 0),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+		}
+		if i%10 == 0 {
+			r.Code, r.Error = 503, "503 Service <Unavailable>"
+		}
+		m.Add(&r)
+	}
+	m.Close()
+
+	var b bytes.Buffer
+	if err := NewHTMLReporter(&m, "TestHTMLReporter").Report(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	got := b.String()
+	for _, want := range []string{
+		"<title>TestHTMLReporter</title>",
+		"<tr><th>Requests</th><td class=\"num\">100</td></tr>",
+		"<tr><td>503</td><td class=\"num\">10</td><td class=\"num\">10.00</td></tr>",
+		"<li>503 Service &lt;Unavailable&gt;</li>",
+		"const hist = {\"lower\":[1,",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report doesn't contain %q", want)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	var m vegeta.Metrics
+	m.Histogram = &vegeta.Histogram{Buckets: vegeta.Buckets{0, 10 * time.Millisecond, 50 * time.Millisecond}}
+	for _, l := range []time.Duration{1, 5, 20, 30, 60} {
+		m.Add(&vegeta.Result{Latency: l * time.Millisecond})
+	}
+	m.Close()
+
+	want := histogramData{
+		Lower:  []float64{0, 10, 50},
+		Upper:  []float64{10, 50, 60},
+		Counts: []float64{2, 2, 1},
+	}
+
+	if got := histogram(&m); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	m.Histogram = nil
+	var total float64
+	h := histogram(&m)
+	for _, c := range h.Counts {
+		total += c
+	}
+
+	if len(h.Counts) != histogramBins || h.Lower[0] != 1 || h.Upper[histogramBins-1] != 60 {
+		t.Errorf("got bins from %v to %v, want %d bins from 1 to 60", h.Lower[0], h.Upper[len(h.Upper)-1], histogramBins)
+	}
+
+	if int(total+0.5) != 5 {
+		t.Errorf("got %v total count, want 5", total)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,13 +20,25 @@ type Metrics struct {
 	requestBytesInCounter   *prometheus.CounterVec
 	requestBytesOutCounter  *prometheus.CounterVec
 	requestFailCounter      *prometheus.CounterVec
+	urls                    *vegeta.URLNormalizer
+
+	mu       sync.Mutex
+	messages map[string]struct{} // Distinct message label values.
 }
 
-// NewMetrics returns a new Metrics instance that must be
-// registered in a Prometheus registry with Register.
-func NewMetrics() *Metrics {
-	baseLabels := []string{"method", "url", "status"}
-	return &Metrics{
+// DefaultMaxURLs is a sensible maximum number of distinct url label values,
+// to be set as the Max of the URLNormalizer of Metrics.
+const DefaultMaxURLs = 1000
+
+// maxMessages is the maximum number of distinct message label values of
+// failed requests, beyond which they're counted as vegeta.OtherGroup.
+const maxMessages = 100
+
+// NewMetrics returns a new Metrics instance, configured with the given
+// options, that must be registered in a Prometheus registry with Register.
+func NewMetrics(opts ...func(*Metrics)) *Metrics {
+	baseLabels := []string{"attack", "method", "url", "status"}
+	pm := &Metrics{
 		requestLatencyHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "request_seconds",
 			Help:    "Request latency",
@@ -43,6 +57,19 @@ func NewMetrics() *Metrics {
 			Help: "Count of failed requests",
 		}, append(baseLabels[:len(baseLabels):len(baseLabels)], "message")),
 	}
+
+	for _, opt := range opts {
+		opt(pm)
+	}
+
+	return pm
+}
+
+// NormalizeURLs returns a functional option which makes Metrics label series
+// with URLs normalized by the given URLNormalizer instead of full ones, such
+// as to bound the cardinality of the series of templated targets with IDs.
+func NormalizeURLs(n *vegeta.URLNormalizer) func(*Metrics) {
+	return func(pm *Metrics) { pm.urls = n }
 }
 
 // Register registers all Prometheus metrics in r.
@@ -63,11 +90,124 @@ func (pm *Metrics) Register(r prometheus.Registerer) error {
 // Observe metrics given a vegeta.Result.
 func (pm *Metrics) Observe(res *vegeta.Result) {
 	code := strconv.FormatUint(uint64(res.Code), 10)
-	pm.requestBytesInCounter.WithLabelValues(res.Method, res.URL, code).Add(float64(res.BytesIn))
-	pm.requestBytesOutCounter.WithLabelValues(res.Method, res.URL, code).Add(float64(res.BytesOut))
-	pm.requestLatencyHistogram.WithLabelValues(res.Method, res.URL, code).Observe(res.Latency.Seconds())
+	url := res.URL
+	if pm.urls != nil {
+		url = pm.urls.Normalize(url)
+	}
+	pm.requestBytesInCounter.WithLabelValues(res.Attack, res.Method, url, code).Add(float64(res.BytesIn))
+	pm.requestBytesOutCounter.WithLabelValues(res.Attack, res.Method, url, code).Add(float64(res.BytesOut))
+	pm.requestLatencyHistogram.WithLabelValues(res.Attack, res.Method, url, code).Observe(res.Latency.Seconds())
 	if res.Error != "" {
-		pm.requestFailCounter.WithLabelValues(res.Method, res.URL, code, res.Error)
+		pm.requestFailCounter.WithLabelValues(res.Attack, res.Method, url, code, pm.message(res, url)).Inc()
+	}
+}
+
+// message returns the message label value of the given failed Result, whose
+// URL is replaced by the given url label value if it was normalized, since
+// errors of the HTTP client quote it.
+func (pm *Metrics) message(res *vegeta.Result, url string) string {
+	msg := res.Error
+	if url != res.URL && res.URL != "" {
+		msg = strings.ReplaceAll(msg, res.URL, url)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.messages[msg]; ok {
+		return msg
+	}
+
+	if len(pm.messages) >= maxMessages {
+		return vegeta.OtherGroup
+	}
+
+	if pm.messages == nil {
+		pm.messages = map[string]struct{}{}
+	}
+	pm.messages[msg] = struct{}{}
+
+	return msg
+}
+
+// AttackerCollector is a prometheus.Collector of gauges of the AttackStats
+// of the attacks run by an Attacker, labeled by attack name, which tell
+// whether the Attacker itself is saturated during a live attack, as well as
+// of the counter of their hits, whose rate is the one achieved.
+type AttackerCollector struct {
+	a *vegeta.Attacker
+
+	inFlight   *prometheus.Desc
+	workers    *prometheus.Desc
+	targetRate *prometheus.Desc
+	hits       *prometheus.Desc
+	lag        *prometheus.Desc
+}
+
+// NewAttackerCollector returns a new AttackerCollector of the given Attacker,
+// which must be registered in a Prometheus registry.
+func NewAttackerCollector(a *vegeta.Attacker) *AttackerCollector {
+	labels := []string{"attack"}
+	return &AttackerCollector{
+		a:          a,
+		inFlight:   prometheus.NewDesc("attack_in_flight_requests", "Requests being sent or whose response is being read", labels, nil),
+		workers:    prometheus.NewDesc("attack_workers", "Workers of the attack", labels, nil),
+		targetRate: prometheus.NewDesc("attack_target_rate", "Hit rate per second of the attack's pacer", labels, nil),
+		hits:       prometheus.NewDesc("attack_hits_total", "Hits sent to the attack's workers or dropped", labels, nil),
+		lag:        prometheus.NewDesc("attack_pacing_lag_seconds", "How late the pending or last hit of the attack is", labels, nil),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *AttackerCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.inFlight, c.workers, c.targetRate, c.hits, c.lag} {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface. The stats of the
+// attacks with the same name, including finished ones, are added up, except
+// for the lag, which is the maximum of the running ones, or the one of the
+// latest if none is.
+func (c *AttackerCollector) Collect(ch chan<- prometheus.Metric) {
+	type metrics struct {
+		inFlight, workers, targetRate, hits, lag float64
+		running                                  bool
+	}
+
+	var (
+		names  []string
+		byName = map[string]*metrics{}
+	)
+
+	for _, s := range c.a.Stats() {
+		m, ok := byName[s.Name]
+		if !ok {
+			m = &metrics{}
+			byName[s.Name] = m
+			names = append(names, s.Name)
+		}
+
+		m.inFlight += float64(s.InFlight)
+		m.workers += float64(s.Workers)
+		m.targetRate += s.TargetRate
+		m.hits += float64(s.Hits)
+
+		if !s.Done {
+			m.lag = max(m.lag, s.Lag.Seconds())
+			m.running = true
+		} else if !m.running {
+			m.lag = s.Lag.Seconds()
+		}
+	}
+
+	for _, name := range names {
+		m := byName[name]
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, m.inFlight, name)
+		ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, m.workers, name)
+		ch <- prometheus.MustNewConstMetric(c.targetRate, prometheus.GaugeValue, m.targetRate, name)
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, m.hits, name)
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, m.lag, name)
 	}
 }
 
@@ -1,6 +1,7 @@
 package prom
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -88,3 +89,180 @@ func TestMetrics_Observe(t *testing.T) {
 		t.Errorf("missing metrics: %v", want)
 	}
 }
+
+func TestMetricsNormalizeURLs(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	pm := NewMetrics(NormalizeURLs(&vegeta.URLNormalizer{DetectIDs: true, Max: 2}))
+	if err := pm.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []string{
+		"http://test.com/users/1",
+		"http://test.com/users/2",
+		"http://test.com/orders/3",
+		"http://test.com/carts/4",
+		"http://test.com/carts/5",
+	} {
+		pm.Observe(&vegeta.Result{Attack: "ci", Method: "GET", URL: u, Code: 200})
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]uint64{}
+	for _, mf := range mfs {
+		if mf.GetName() != "request_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "url" {
+					counts[l.GetValue()] = m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+
+	want := map[string]uint64{
+		"http://test.com/users/:id":  2,
+		"http://test.com/orders/:id": 1,
+		vegeta.OtherGroup:            2,
+	}
+
+	if len(counts) != len(want) {
+		t.Fatalf("got url counts %v, want %v", counts, want)
+	}
+
+	for u, n := range want {
+		if counts[u] != n {
+			t.Errorf("url %s: got %d requests, want %d", u, counts[u], n)
+		}
+	}
+}
+
+func TestMetricsFailMessages(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	pm := NewMetrics(NormalizeURLs(&vegeta.URLNormalizer{DetectIDs: true}))
+	if err := pm.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		u := fmt.Sprintf("http://test.com/users/%d", i)
+		pm.Observe(&vegeta.Result{Method: "GET", URL: u, Error: fmt.Sprintf("Get %q: connection refused", u)})
+	}
+
+	// Distinct messages beyond the maximum are counted together.
+	for i := 0; i < maxMessages+10; i++ {
+		pm.Observe(&vegeta.Result{Method: "GET", URL: "http://test.com/", Code: 500, Error: fmt.Sprintf("error %d", i)})
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]float64{}
+	for _, mf := range mfs {
+		if mf.GetName() != "request_fail_count" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "message" {
+					counts[l.GetValue()] = m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	if len(counts) != maxMessages+1 {
+		t.Errorf("got %d messages, want %d", len(counts), maxMessages+1)
+	}
+
+	for msg, want := range map[string]float64{
+		`Get "http://test.com/users/:id": connection refused`: 3,
+		"error 0":         1,
+		vegeta.OtherGroup: 11,
+	} {
+		if got := counts[msg]; got != want {
+			t.Errorf("message %q: got %v failures, want %v", msg, got, want)
+		}
+	}
+}
+
+func TestAttackerCollector(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	a := vegeta.NewAttacker(vegeta.Workers(1), vegeta.MaxWorkers(1))
+	if err := reg.Register(NewAttackerCollector(a)); err != nil {
+		t.Fatal(err)
+	}
+
+	gather := func() map[string]float64 {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := map[string]float64{}
+		for _, mf := range mfs {
+			for _, m := range mf.GetMetric() {
+				if l := m.GetLabel(); len(l) != 1 || l[0].GetName() != "attack" || l[0].GetValue() != "ci" {
+					t.Errorf("metric %s has labels %v", mf.GetName(), l)
+				}
+				values[mf.GetName()] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+			}
+		}
+		return values
+	}
+
+	if gauges := gather(); len(gauges) != 0 {
+		t.Fatalf("got metrics %v before attacking, want none", gauges)
+	}
+
+	tr := vegeta.NewStaticTargeter(vegeta.Target{Method: "GET", URL: srv.URL})
+	res := a.Attack(tr, vegeta.Rate{Freq: 50, Per: time.Second}, 0, "ci")
+
+	var gauges map[string]float64
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if gauges = gather(); gauges["attack_in_flight_requests"] == 1 && gauges["attack_pacing_lag_seconds"] > 0.05 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("attack didn't saturate: %v", gauges)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if gauges["attack_workers"] != 1 || gauges["attack_target_rate"] != 50 || gauges["attack_hits_total"] != 1 {
+		t.Errorf("unexpected metrics: %v", gauges)
+	}
+
+	// No hits are sent while the only worker is stuck.
+	time.Sleep(20 * time.Millisecond)
+	if gauges = gather(); gauges["attack_hits_total"] != 1 {
+		t.Errorf("got %v hits while stuck, want 1", gauges["attack_hits_total"])
+	}
+
+	a.Stop()
+	close(release)
+	for range res {
+	}
+
+	// Finished attacks are still collected, such as for a final push.
+	gauges = gather()
+	if gauges["attack_hits_total"] < 1 || gauges["attack_workers"] != 0 || gauges["attack_in_flight_requests"] != 0 || gauges["attack_target_rate"] != 0 {
+		t.Errorf("unexpected metrics after attacking: %v", gauges)
+	}
+}
@@ -0,0 +1,202 @@
+package prom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// A Pusher pushes the metrics gathered from a Prometheus registry to a
+// remote endpoint, for attacks too short-lived to be scraped.
+type Pusher interface {
+	Push(ctx context.Context) error
+}
+
+// PushOpts configures a Pusher.
+type PushOpts struct {
+	// URL is the endpoint to push metrics to.
+	URL string
+	// Job is the value of the job label of the pushed metrics.
+	Job string
+	// Instance is the value of the instance label of the pushed metrics.
+	Instance string
+	// Client is the HTTP client used to push. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (o PushOpts) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return http.DefaultClient
+}
+
+// NewPushgatewayPusher returns a Pusher that pushes the metrics gathered
+// from g to a Prometheus Pushgateway, replacing those previously pushed with
+// the same job and instance labels.
+func NewPushgatewayPusher(g prometheus.Gatherer, opts PushOpts) Pusher {
+	p := push.New(opts.URL, opts.Job).Gatherer(g).Client(opts.client())
+	if opts.Instance != "" {
+		p = p.Grouping("instance", opts.Instance)
+	}
+	return pushgatewayPusher{p}
+}
+
+type pushgatewayPusher struct{ p *push.Pusher }
+
+func (p pushgatewayPusher) Push(ctx context.Context) error {
+	return p.p.PushContext(ctx)
+}
+
+// NewRemoteWritePusher returns a Pusher that writes samples of the metrics
+// gathered from g, timestamped at the time of each push, to a Prometheus
+// remote-write endpoint, such as the one of a Prometheus server with the
+// remote write receiver enabled, Mimir, Thanos or VictoriaMetrics.
+func NewRemoteWritePusher(g prometheus.Gatherer, opts PushOpts) Pusher {
+	return &remoteWritePusher{g: g, opts: opts, now: time.Now}
+}
+
+type remoteWritePusher struct {
+	g    prometheus.Gatherer
+	opts PushOpts
+	now  func() time.Time
+}
+
+func (p *remoteWritePusher) Push(ctx context.Context) error {
+	mfs, err := p.g.Gather()
+	if err != nil {
+		return err
+	}
+
+	req := prompb.WriteRequest{
+		Timeseries: timeSeries(mfs, p.opts.Job, p.opts.Instance, p.now().UnixMilli()),
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opts.URL, bytes.NewReader(snappy.Encode(nil, data)))
+	if err != nil {
+		return err
+	}
+
+	hreq.Header.Set("Content-Encoding", "snappy")
+	hreq.Header.Set("Content-Type", "application/x-protobuf")
+	hreq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := p.opts.client().Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code %d while pushing to %s: %s", resp.StatusCode, p.opts.URL, body)
+	}
+
+	return nil
+}
+
+// timeSeries converts the given metric families into remote-write time
+// series with the given job and instance labels and timestamp in
+// milliseconds. Histograms and summaries are split into a series per bucket
+// or quantile, as well as _sum and _count series, like when scraped.
+func timeSeries(mfs []*dto.MetricFamily, job, instance string, ts int64) []prompb.TimeSeries {
+	var series []prompb.TimeSeries
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			add := func(suffix string, v float64, extra ...prompb.Label) {
+				labels := []prompb.Label{{Name: "__name__", Value: name + suffix}}
+				if job != "" {
+					labels = append(labels, prompb.Label{Name: "job", Value: job})
+				}
+				if instance != "" {
+					labels = append(labels, prompb.Label{Name: "instance", Value: instance})
+				}
+				for _, lp := range m.GetLabel() {
+					labels = append(labels, prompb.Label{Name: lp.GetName(), Value: lp.GetValue()})
+				}
+				labels = append(labels, extra...)
+				sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+				series = append(series, prompb.TimeSeries{
+					Labels:  labels,
+					Samples: []prompb.Sample{{Value: v, Timestamp: ts}},
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add("_bucket", float64(b.GetCumulativeCount()), le(b.GetUpperBound()))
+				}
+				add("_bucket", float64(h.GetSampleCount()), le(math.Inf(1)))
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), prompb.Label{Name: "quantile", Value: formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			default:
+				add("", m.GetUntyped().GetValue())
+			}
+		}
+	}
+	return series
+}
+
+func le(bound float64) prompb.Label {
+	return prompb.Label{Name: "le", Value: formatFloat(bound)}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// PushEvery pushes metrics with p at every given interval until ctx is
+// done, calling the given function with every push error, and then pushes
+// them a final time, returning its error.
+func PushEvery(ctx context.Context, p Pusher, every time.Duration, errs func(error)) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			return p.Push(ctx)
+		case <-ticker.C:
+			if err := p.Push(ctx); err != nil && ctx.Err() == nil {
+				errs(err)
+			}
+		}
+	}
+}
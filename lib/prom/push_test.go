@@ -0,0 +1,198 @@
+package prom
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func newTestRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	reg := prometheus.NewRegistry()
+	pm := NewMetrics()
+	if err := pm.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	pm.Observe(&vegeta.Result{
+		URL:      "http://test.com/test1",
+		Method:   "GET",
+		Code:     200,
+		Latency:  100 * time.Millisecond,
+		BytesIn:  1000,
+		BytesOut: 50,
+	})
+
+	return reg
+}
+
+func TestPushgatewayPusher(t *testing.T) {
+	t.Parallel()
+
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := NewPushgatewayPusher(newTestRegistry(t), PushOpts{URL: srv.URL, Job: "vegeta", Instance: "ci-1"})
+	if err := p.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("got method %s, want PUT", method)
+	}
+
+	if want := "/metrics/job/vegeta/instance/ci-1"; path != want {
+		t.Errorf("got path %q, want %q", path, want)
+	}
+
+	if !strings.Contains(body, "request_bytes_in") {
+		t.Errorf("pushed body doesn't contain request_bytes_in")
+	}
+}
+
+func TestRemoteWritePusher(t *testing.T) {
+	t.Parallel()
+
+	var req prompb.WriteRequest
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		compressed, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, compressed)
+		if err == nil {
+			err = req.Unmarshal(data)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	p := NewRemoteWritePusher(newTestRegistry(t), PushOpts{URL: srv.URL, Job: "vegeta", Instance: "ci-1"})
+	p.(*remoteWritePusher).now = func() time.Time { return now }
+
+	if err := p.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if got := headers.Get(name); got != want {
+			t.Errorf("header %s: got %q, want %q", name, got, want)
+		}
+	}
+
+	series := map[string]float64{}
+	for _, ts := range req.Timeseries {
+		var name, le string
+		labels := map[string]string{}
+		for i, l := range ts.Labels {
+			if i > 0 && ts.Labels[i-1].Name >= l.Name {
+				t.Errorf("labels aren't sorted: %v", ts.Labels)
+			}
+			labels[l.Name] = l.Value
+		}
+		name, le = labels["__name__"], labels["le"]
+
+		if labels["job"] != "vegeta" || labels["instance"] != "ci-1" || labels["url"] != "http://test.com/test1" {
+			t.Errorf("series %s has labels %v", name, labels)
+		}
+
+		if len(ts.Samples) != 1 || ts.Samples[0].Timestamp != now.UnixMilli() {
+			t.Errorf("series %s has samples %v", name, ts.Samples)
+			continue
+		}
+
+		if le != "" {
+			name += "{le=" + le + "}"
+		}
+		series[name] = ts.Samples[0].Value
+	}
+
+	for name, want := range map[string]float64{
+		"request_bytes_in":                1000,
+		"request_bytes_out":               50,
+		"request_seconds_count":           1,
+		"request_seconds_sum":             0.1,
+		"request_seconds_bucket{le=0.05}": 0,
+		"request_seconds_bucket{le=0.1}":  1,
+		"request_seconds_bucket{le=+Inf}": 1,
+	} {
+		if got, ok := series[name]; !ok || got != want {
+			t.Errorf("series %s: got %v (found: %t), want %v", name, got, ok, want)
+		}
+	}
+}
+
+func TestRemoteWritePusherError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	p := NewRemoteWritePusher(newTestRegistry(t), PushOpts{URL: srv.URL})
+	err := p.Push(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "out of order sample") {
+		t.Errorf("got error %v, want one with the status code and body", err)
+	}
+}
+
+type pusherFunc func(context.Context) error
+
+func (f pusherFunc) Push(ctx context.Context) error { return f(ctx) }
+
+func TestPushEvery(t *testing.T) {
+	t.Parallel()
+
+	var pushes atomic.Int64
+	p := pusherFunc(func(ctx context.Context) error {
+		pushes.Add(1)
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- PushEvery(ctx, p, 10*time.Millisecond, func(err error) { t.Error(err) })
+	}()
+
+	for pushes.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	n := pushes.Load()
+	cancel()
+
+	// The final push happens with a fresh context after cancellation.
+	if err := <-done; err != nil {
+		t.Fatalf("final push: %v", err)
+	}
+
+	if got := pushes.Load(); got <= n {
+		t.Errorf("got %d pushes, want a final one after %d", got, n)
+	}
+}
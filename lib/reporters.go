@@ -67,16 +67,16 @@ func NewTextReporter(m *Metrics) Reporter {
 		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
 		if _, err = fmt.Fprintf(tw, fmtstr,
 			m.Requests, m.Rate, m.Throughput,
-			round(m.Duration+m.Wait),
-			round(m.Duration),
-			round(m.Wait),
-			round(m.Latencies.Min),
-			round(m.Latencies.Mean),
-			round(m.Latencies.P50),
-			round(m.Latencies.P90),
-			round(m.Latencies.P95),
-			round(m.Latencies.P99),
-			round(m.Latencies.Max),
+			RoundDuration(m.Duration+m.Wait),
+			RoundDuration(m.Duration),
+			RoundDuration(m.Wait),
+			RoundDuration(m.Latencies.Min),
+			RoundDuration(m.Latencies.Mean),
+			RoundDuration(m.Latencies.P50),
+			RoundDuration(m.Latencies.P90),
+			RoundDuration(m.Latencies.P95),
+			RoundDuration(m.Latencies.P99),
+			RoundDuration(m.Latencies.Max),
 			m.BytesIn.Total, m.BytesIn.Mean,
 			m.BytesOut.Total, m.BytesOut.Mean,
 			m.Success*100,
@@ -112,6 +112,63 @@ func NewTextReporter(m *Metrics) Reporter {
 	}
 }
 
+// NewMarkdownReporter returns a Reporter that writes out Metrics as Markdown
+// tables, which render well in pull requests and documents.
+func NewMarkdownReporter(m *Metrics) Reporter {
+	return func(w io.Writer) (err error) {
+		var b strings.Builder
+
+		b.WriteString("| Requests | Rate | Throughput | Duration | Attack | Wait | Success |\n")
+		b.WriteString("| ---: | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+		fmt.Fprintf(&b, "| %d | %.2f | %.2f | %s | %s | %s | %.2f%% |\n\n",
+			m.Requests, m.Rate, m.Throughput,
+			RoundDuration(m.Duration+m.Wait), RoundDuration(m.Duration), RoundDuration(m.Wait),
+			m.Success*100,
+		)
+
+		b.WriteString("| | Min | Mean | 50 | 90 | 95 | 99 | Max |\n")
+		b.WriteString("| --- | ---: | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+		fmt.Fprintf(&b, "| Latency | %s | %s | %s | %s | %s | %s | %s |\n\n",
+			RoundDuration(m.Latencies.Min),
+			RoundDuration(m.Latencies.Mean),
+			RoundDuration(m.Latencies.P50),
+			RoundDuration(m.Latencies.P90),
+			RoundDuration(m.Latencies.P95),
+			RoundDuration(m.Latencies.P99),
+			RoundDuration(m.Latencies.Max),
+		)
+
+		b.WriteString("| Bytes | Total | Mean |\n")
+		b.WriteString("| --- | ---: | ---: |\n")
+		fmt.Fprintf(&b, "| In | %d | %.2f |\n", m.BytesIn.Total, m.BytesIn.Mean)
+		fmt.Fprintf(&b, "| Out | %d | %.2f |\n\n", m.BytesOut.Total, m.BytesOut.Mean)
+
+		codes := make([]string, 0, len(m.StatusCodes))
+		for code := range m.StatusCodes {
+			codes = append(codes, code)
+		}
+
+		sort.Strings(codes)
+
+		b.WriteString("| Status Code | Count | % |\n")
+		b.WriteString("| --- | ---: | ---: |\n")
+		for _, code := range codes {
+			count := m.StatusCodes[code]
+			fmt.Fprintf(&b, "| %s | %d | %.2f%% |\n", code, count, 100*float64(count)/float64(max(m.Requests, 1)))
+		}
+
+		if len(m.Errors) > 0 {
+			b.WriteString("\n**Errors**\n\n")
+			for _, e := range m.Errors {
+				fmt.Fprintf(&b, "- `%s`\n", strings.ReplaceAll(e, "`", "'"))
+			}
+		}
+
+		_, err = io.WriteString(w, b.String())
+		return err
+	}
+}
+
 var durations = [...]time.Duration{
 	time.Hour,
 	time.Minute,
@@ -121,8 +178,9 @@ var durations = [...]time.Duration{
 	time.Nanosecond,
 }
 
-// round to the next most precise unit
-func round(d time.Duration) time.Duration {
+// RoundDuration rounds the given duration to the next most precise unit
+// than its own, as durations are shown in text reports.
+func RoundDuration(d time.Duration) time.Duration {
 	for i, unit := range durations {
 		if d >= unit && i < len(durations)-1 {
 			return d.Round(durations[i+1])
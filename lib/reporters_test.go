@@ -0,0 +1,47 @@
+package vegeta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownReporter(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	for i, code := range []uint16{200, 200, 200, 500} {
+		r := &Result{
+			Code:      code,
+			Timestamp: time.Unix(int64(i), 0),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+			BytesIn:   10,
+		}
+		if code != 200 {
+			r.Error = "500 `Internal` Server Error"
+		}
+		m.Add(r)
+	}
+	m.Close()
+
+	var buf bytes.Buffer
+	if err := NewMarkdownReporter(&m).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"| Requests | Rate | Throughput | Duration | Attack | Wait | Success |\n",
+		"| 4 | 1.33 | 1.00 | 3.004s | 3s | 4ms | 75.00% |\n",
+		"| Latency | 1ms | 2.5ms |",
+		"| In | 40 | 10.00 |\n",
+		"| 200 | 3 | 75.00% |\n",
+		"| 500 | 1 | 25.00% |\n",
+		"- `500 'Internal' Server Error`\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report doesn't contain %q:\n%s", want, got)
+		}
+	}
+}
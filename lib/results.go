@@ -22,7 +22,9 @@ func init() {
 	gob.Register(&Result{})
 }
 
-// Result contains the results of a single Target hit.
+// Result contains the results of a single Target hit. Its BytesIn is the
+// length of the whole response body, even when only up to the Attacker's
+// MaxBody of it is captured in Body.
 type Result struct {
 	Attack    string        `json:"attack"`
 	Seq       uint64        `json:"seq"`
@@ -36,6 +38,10 @@ type Result struct {
 	Method    string        `json:"method"`
 	URL       string        `json:"url"`
 	Headers   http.Header   `json:"headers"`
+	LocalAddr string        `json:"local_addr"`
+	BodyHash  string        `json:"body_hash"`
+	TraceID   string        `json:"trace_id"`
+	SpanID    string        `json:"span_id"`
 }
 
 // End returns the time at which a Result ended.
@@ -54,7 +60,11 @@ func (r Result) Equal(other Result) bool {
 		bytes.Equal(r.Body, other.Body) &&
 		r.Method == other.Method &&
 		r.URL == other.URL &&
-		headerEqual(r.Headers, other.Headers)
+		headerEqual(r.Headers, other.Headers) &&
+		r.LocalAddr == other.LocalAddr &&
+		r.BodyHash == other.BodyHash &&
+		r.TraceID == other.TraceID &&
+		r.SpanID == other.SpanID
 }
 
 func headerEqual(h1, h2 http.Header) bool {
@@ -167,7 +177,9 @@ func (enc Encoder) Encode(r *Result) error { return enc(r) }
 // NewCSVEncoder returns an Encoder that dumps the given *Result as a CSV
 // record. The columns are: UNIX timestamp in ns since epoch,
 // HTTP status code, request latency in ns, bytes out, bytes in,
-// response body, and lastly the error.
+// error, response body, attack name, sequence number, method, URL,
+// response headers, local address, response body hash, trace ID and lastly
+// the span ID.
 func NewCSVEncoder(w io.Writer) Encoder {
 	enc := csv.NewWriter(w)
 	return func(r *Result) error {
@@ -184,6 +196,10 @@ func NewCSVEncoder(w io.Writer) Encoder {
 			r.Method,
 			r.URL,
 			base64.StdEncoding.EncodeToString(headerBytes(r.Headers)),
+			r.LocalAddr,
+			r.BodyHash,
+			r.TraceID,
+			r.SpanID,
 		})
 		if err != nil {
 			return err
@@ -204,16 +220,23 @@ func headerBytes(h http.Header) []byte {
 	return append(hdr.Bytes(), '\r', '\n')
 }
 
+// csvMinFields is the number of columns of CSV encoded Results written
+// before any newer optional ones were added.
+const csvMinFields = 12
+
 // NewCSVDecoder returns a Decoder that decodes CSV encoded Results.
+// Records written before newer trailing columns were added are still decoded.
 func NewCSVDecoder(r io.Reader) Decoder {
 	dec := csv.NewReader(r)
-	dec.FieldsPerRecord = 12
+	dec.FieldsPerRecord = -1
 	dec.TrimLeadingSpace = true
 
 	return func(r *Result) error {
 		rec, err := dec.Read()
 		if err != nil {
 			return err
+		} else if len(rec) < csvMinFields {
+			return csv.ErrFieldCount
 		}
 
 		ts, err := strconv.ParseInt(rec[0], 10, 64)
@@ -265,6 +288,22 @@ func NewCSVDecoder(r io.Reader) Decoder {
 			r.Headers = http.Header(hdr)
 		}
 
+		if len(rec) > 12 {
+			r.LocalAddr = rec[12]
+		}
+
+		if len(rec) > 13 {
+			r.BodyHash = rec[13]
+		}
+
+		if len(rec) > 14 {
+			r.TraceID = rec[14]
+		}
+
+		if len(rec) > 15 {
+			r.SpanID = rec[15]
+		}
+
 		return err
 	}
 }
@@ -105,6 +105,14 @@ func easyjsonBd1621b8DecodeGithubComTsenartVegetaV12Lib(in *jlexer.Lexer, out *j
 				}
 				in.Delim('}')
 			}
+		case "local_addr":
+			out.LocalAddr = string(in.String())
+		case "body_hash":
+			out.BodyHash = string(in.String())
+		case "trace_id":
+			out.TraceID = string(in.String())
+		case "span_id":
+			out.SpanID = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -206,6 +214,26 @@ func easyjsonBd1621b8EncodeGithubComTsenartVegetaV12Lib(out *jwriter.Writer, in
 			out.RawByte('}')
 		}
 	}
+	{
+		const prefix string = ",\"local_addr\":"
+		out.RawString(prefix)
+		out.String(string(in.LocalAddr))
+	}
+	{
+		const prefix string = ",\"body_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.BodyHash))
+	}
+	{
+		const prefix string = ",\"trace_id\":"
+		out.RawString(prefix)
+		out.String(string(in.TraceID))
+	}
+	{
+		const prefix string = ",\"span_id\":"
+		out.RawString(prefix)
+		out.String(string(in.SpanID))
+	}
 	out.RawByte('}')
 }
 
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -99,7 +100,11 @@ func TestResultEncoding(t *testing.T) {
 					Body:      rapid.SliceOf(rapid.Byte()).Draw(t, "body"),
 					Method: rapid.StringMatching("^(GET|PUT|POST|DELETE|HEAD|OPTIONS)$").
 						Draw(t, "method"),
-					URL: rapid.StringMatching(`^(https?):\/\/([a-zA-Z0-9-\.]+)(:[0-9]{1,5})?\/?([a-zA-Z0-9\-\._\?\,\'\/\\\+&amp;%\$#\=~]*)$`).Draw(t, "url"),
+					URL:       rapid.StringMatching(`^(https?):\/\/([a-zA-Z0-9-\.]+)(:[0-9]{1,5})?\/?([a-zA-Z0-9\-\._\?\,\'\/\\\+&amp;%\$#\=~]*)$`).Draw(t, "url"),
+					LocalAddr: rapid.StringMatching(`^((\d{1,3}\.){3}\d{1,3})?$`).Draw(t, "local_addr"),
+					BodyHash:  rapid.StringMatching(`^([0-9a-f]{16})?$`).Draw(t, "body_hash"),
+					TraceID:   rapid.StringMatching(`^([0-9a-f]{32})?$`).Draw(t, "trace_id"),
+					SpanID:    rapid.StringMatching(`^([0-9a-f]{16})?$`).Draw(t, "span_id"),
 				}
 
 				if len(hdrs) > 0 {
@@ -142,6 +147,40 @@ func TestResultEncoding(t *testing.T) {
 	}
 }
 
+func TestCSVDecoder_OlderRecords(t *testing.T) {
+	t.Parallel()
+
+	// A record written before the local address column was added.
+	const rec = "1000000000,200,1000,10,20,,,atk,1,GET,http://localhost,\n"
+
+	var r Result
+	if err := NewCSVDecoder(strings.NewReader(rec))(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Result{
+		Attack:    "atk",
+		Seq:       1,
+		Code:      200,
+		Timestamp: time.Unix(1, 0),
+		Latency:   1000,
+		BytesOut:  10,
+		BytesIn:   20,
+		Method:    "GET",
+		URL:       "http://localhost",
+		Body:      []byte{},
+	}
+
+	if !r.Equal(want) {
+		t.Errorf("mismatch: %s", cmp.Diff(want, r))
+	}
+
+	const short = "1000000000,200,1000\n"
+	if err := NewCSVDecoder(strings.NewReader(short))(&r); err == nil {
+		t.Error("want error decoding record with too few fields")
+	}
+}
+
 func BenchmarkResultEncodings(b *testing.B) {
 	b.StopTimer()
 	b.ResetTimer()
@@ -0,0 +1,231 @@
+// Package statsd implements a sink of attack results which sends metrics of
+// them to a StatsD or DogStatsD server.
+package statsd
+
+import (
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// DefaultPrefix is the default prefix of metric names.
+const DefaultPrefix = "vegeta."
+
+// maxPacketSize is the maximum size of the UDP packets sent, which fits
+// in the usual Ethernet MTU.
+const maxPacketSize = 1432
+
+// Opts configures a Sink.
+type Opts struct {
+	// Prefix is prepended to every metric name. Defaults to DefaultPrefix.
+	Prefix string
+	// DogStatsD enables DogStatsD tags.
+	DogStatsD bool
+	// Tags are added to every metric with DogStatsD.
+	Tags map[string]string
+	// Every is the interval of the aggregate metrics sent instead of the
+	// metrics of each Result, if positive.
+	Every time.Duration
+}
+
+// Sink sends metrics of observed Results, or aggregate metrics of every time
+// interval, in the StatsD protocol, packing them into packets of up to
+// maxPacketSize bytes.
+//
+// Per Result, it sends the requests, responses.<code>, errors, bytes_in and
+// bytes_out counters and the latency timer. With DogStatsD, status codes
+// are sent as a tag of the requests counter instead, and metrics are tagged
+// with the attack name and method too.
+//
+// Per interval, it sends the requests counter and the rate, throughput,
+// success, bytes_in_rate, bytes_out_rate and latency.<stat> gauges, with
+// latencies in milliseconds.
+//
+// As is conventional for StatsD clients, packets which fail to be sent,
+// such as when no server is listening, are dropped.
+type Sink struct {
+	w       io.Writer
+	opts    Opts
+	tags    string
+	windows *vegeta.Windows
+	packet  []byte
+	line    []byte
+}
+
+// Dial returns a new Sink which sends metrics over UDP to the StatsD server
+// at the given address.
+func Dial(addr string, opts Opts) (*Sink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(conn, opts), nil
+}
+
+// NewSink returns a new Sink which writes each packet of metrics to w.
+func NewSink(w io.Writer, opts Opts) *Sink {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+
+	s := &Sink{w: w, opts: opts}
+
+	keys := make([]string, 0, len(opts.Tags))
+	for k := range opts.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+":"+opts.Tags[k])
+	}
+	s.tags = strings.Join(tags, ",")
+
+	if opts.Every > 0 {
+		s.windows = vegeta.NewWindows(opts.Every)
+	}
+
+	return s
+}
+
+// Observe sends the metrics of the given Result, or the aggregate metrics
+// of the intervals completed by it.
+func (s *Sink) Observe(r *vegeta.Result) {
+	if s.windows != nil {
+		s.windows.Add(r)
+		s.sendWindows()
+		s.flush()
+		return
+	}
+
+	code := strconv.FormatUint(uint64(r.Code), 10)
+
+	var tags []string
+	if s.opts.DogStatsD {
+		if r.Attack != "" {
+			tags = append(tags, "attack:"+r.Attack)
+		}
+		if r.Method != "" {
+			tags = append(tags, "method:"+r.Method)
+		}
+		s.metric("requests", "1", "c", append(tags, "status:"+code)...)
+	} else {
+		s.metric("requests", "1", "c")
+		s.metric("responses."+code, "1", "c")
+	}
+
+	if r.Error != "" {
+		s.metric("errors", "1", "c", tags...)
+	}
+
+	s.metric("latency", formatMillis(r.Latency), "ms", tags...)
+	s.metric("bytes_in", strconv.FormatUint(r.BytesIn, 10), "c", tags...)
+	s.metric("bytes_out", strconv.FormatUint(r.BytesOut, 10), "c", tags...)
+	s.flush()
+}
+
+func (s *Sink) sendWindows() {
+	for _, m := range s.windows.Complete() {
+		s.metric("requests", strconv.FormatUint(m.Requests, 10), "c")
+		s.metric("rate", formatFloat(m.Rate), "g")
+		s.metric("throughput", formatFloat(m.Throughput), "g")
+		s.metric("success", formatFloat(m.Success), "g")
+		s.metric("bytes_in_rate", formatFloat(m.BytesIn), "g")
+		s.metric("bytes_out_rate", formatFloat(m.BytesOut), "g")
+		for _, l := range []struct {
+			stat string
+			d    time.Duration
+		}{
+			{"min", m.Latencies.Min},
+			{"mean", m.Latencies.Mean},
+			{"p50", m.Latencies.P50},
+			{"p90", m.Latencies.P90},
+			{"p95", m.Latencies.P95},
+			{"p99", m.Latencies.P99},
+			{"max", m.Latencies.Max},
+		} {
+			s.metric("latency."+l.stat, formatMillis(l.d), "g")
+		}
+	}
+}
+
+// metric adds a metric line to the current packet, sending it first if the
+// line doesn't fit.
+func (s *Sink) metric(name, value, typ string, tags ...string) {
+	b := append(s.line[:0], s.opts.Prefix...)
+	b = append(b, name...)
+	b = append(b, ':')
+	b = append(b, value...)
+	b = append(b, '|')
+	b = append(b, typ...)
+
+	if s.opts.DogStatsD && (len(tags) > 0 || s.tags != "") {
+		b = append(b, "|#"...)
+		b = append(b, s.tags...)
+		for i, tag := range tags {
+			if i > 0 || s.tags != "" {
+				b = append(b, ',')
+			}
+			b = append(b, tag...)
+		}
+	}
+	s.line = b
+
+	if len(s.packet) > 0 && len(s.packet)+1+len(b) > maxPacketSize {
+		s.flush()
+	}
+
+	if len(s.packet) > 0 {
+		s.packet = append(s.packet, '\n')
+	}
+	s.packet = append(s.packet, b...)
+}
+
+// flush sends the current packet, if any.
+func (s *Sink) flush() {
+	if len(s.packet) == 0 {
+		return
+	}
+
+	_, _ = s.w.Write(s.packet) // Dropped on errors.
+	s.packet = s.packet[:0]
+}
+
+// Late returns the number of Results which weren't aggregated because they
+// arrived after the metrics of their interval were sent.
+func (s *Sink) Late() uint64 {
+	if s.windows == nil {
+		return 0
+	}
+	return s.windows.Late
+}
+
+// Close sends the aggregate metrics of the remaining intervals, if any,
+// and closes the underlying writer if it's an io.Closer.
+func (s *Sink) Close() error {
+	if s.windows != nil {
+		s.windows.Close()
+		s.sendWindows()
+	}
+	s.flush()
+
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func formatMillis(d time.Duration) string {
+	return formatFloat(float64(d) / float64(time.Millisecond))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
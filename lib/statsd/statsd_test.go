@@ -0,0 +1,163 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// packets records each written packet.
+type packets []string
+
+func (ps *packets) Write(p []byte) (int, error) {
+	*ps = append(*ps, string(p))
+	return len(p), nil
+}
+
+func TestSink(t *testing.T) {
+	t.Parallel()
+
+	r := &vegeta.Result{
+		Attack:  "checkout",
+		Method:  "GET",
+		Code:    500,
+		Error:   "500 Internal Server Error",
+		Latency: 1500 * time.Microsecond,
+		BytesIn: 100,
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts Opts
+		want string
+	}{
+		{
+			name: "statsd",
+			want: "vegeta.requests:1|c\nvegeta.responses.500:1|c\nvegeta.errors:1|c\n" +
+				"vegeta.latency:1.5|ms\nvegeta.bytes_in:100|c\nvegeta.bytes_out:0|c",
+		},
+		{
+			name: "dogstatsd",
+			opts: Opts{Prefix: "load.", DogStatsD: true, Tags: map[string]string{"env": "ci"}},
+			want: "load.requests:1|c|#env:ci,attack:checkout,method:GET,status:500\n" +
+				"load.errors:1|c|#env:ci,attack:checkout,method:GET\n" +
+				"load.latency:1.5|ms|#env:ci,attack:checkout,method:GET\n" +
+				"load.bytes_in:100|c|#env:ci,attack:checkout,method:GET\n" +
+				"load.bytes_out:0|c|#env:ci,attack:checkout,method:GET",
+		},
+	} {
+		var ps packets
+		s := NewSink(&ps, tc.opts)
+		s.Observe(r)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ps) != 1 || ps[0] != tc.want {
+			t.Errorf("%s: got packets %q, want %q", tc.name, ps, tc.want)
+		}
+	}
+}
+
+func TestSinkPacketSize(t *testing.T) {
+	t.Parallel()
+
+	var ps packets
+	s := NewSink(&ps, Opts{DogStatsD: true, Tags: map[string]string{"padding": strings.Repeat("x", 400)}})
+	s.Observe(&vegeta.Result{Code: 200})
+	s.Close()
+
+	if len(ps) < 2 {
+		t.Errorf("got %d packets, want the metrics split into several", len(ps))
+	}
+
+	for _, p := range ps {
+		if len(p) > maxPacketSize {
+			t.Errorf("got packet of %d bytes, want at most %d", len(p), maxPacketSize)
+		}
+	}
+}
+
+func TestSinkEvery(t *testing.T) {
+	t.Parallel()
+
+	var ps packets
+	s := NewSink(&ps, Opts{Every: time.Second})
+
+	began := time.Unix(1700000000, 0)
+	for i := 0; i < 20; i++ {
+		s.Observe(&vegeta.Result{
+			Code:      200,
+			Timestamp: began.Add(time.Duration(i) * 100 * time.Millisecond),
+			Latency:   2 * time.Millisecond,
+		})
+	}
+
+	// Once the first interval is sent, a result of it arriving late isn't
+	// aggregated.
+	s.Observe(&vegeta.Result{Code: 200, Timestamp: began.Add(2 * time.Second), Latency: 3 * time.Millisecond})
+	s.Observe(&vegeta.Result{Code: 200, Timestamp: began, Latency: 3 * time.Millisecond})
+	if got := s.Late(); got != 1 {
+		t.Errorf("got %d late results, want 1", got)
+	}
+
+	s.Close()
+
+	got := strings.Join(ps, "\n")
+	for _, want := range []string{
+		"vegeta.requests:10|c\nvegeta.rate:10|g\nvegeta.throughput:10|g\nvegeta.success:1|g\n",
+		"vegeta.latency.p99:2|g\n",
+	} {
+		if strings.Count(got, want) != 2 {
+			t.Errorf("got %q, want %q once per interval", got, want)
+		}
+	}
+}
+
+func TestDial(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := Dial(conn.LocalAddr().String(), Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Observe(&vegeta.Result{Code: 200, Latency: time.Millisecond})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, maxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(buf[:n]); !strings.HasPrefix(got, "vegeta.requests:1|c\n") {
+		t.Errorf("got packet %q", got)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, net.ErrClosed }
+
+func TestSinkDropsPackets(t *testing.T) {
+	t.Parallel()
+
+	s := NewSink(errWriter{}, Opts{})
+	s.Observe(&vegeta.Result{Code: 200})
+	if err := s.Close(); err != nil {
+		t.Errorf("got error %v, want failed packets to be dropped", err)
+	}
+}
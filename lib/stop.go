@@ -0,0 +1,119 @@
+package vegeta
+
+import (
+	"fmt"
+	"time"
+)
+
+// A StopCondition is a condition on the Results of an attack which, once
+// met, makes an Attacker stop early, like a circuit breaker. See StopOn.
+type StopCondition interface {
+	// Check returns a new function which is called serially with every Result
+	// of an attack and returns true once the condition is met.
+	Check() func(*Result) bool
+
+	// String returns a human readable description of the condition.
+	String() string
+}
+
+// ErrorRatio is a StopCondition met when the ratio of Results with errors
+// among the last Window ones exceeds Max.
+type ErrorRatio struct {
+	Max    float64 // Ratio between 0 and 1
+	Window int     // Number of Results, at least 1
+}
+
+// ErrorRatio satisfies the StopCondition interface.
+var _ StopCondition = ErrorRatio{}
+
+// Check implements the StopCondition interface.
+func (c ErrorRatio) Check() func(*Result) bool {
+	var (
+		window = make([]bool, max(c.Window, 1))
+		n      int // Results seen.
+		errs   int // Results with errors in the window.
+	)
+
+	return func(r *Result) bool {
+		i := n % len(window)
+		if window[i] {
+			errs--
+		}
+
+		if window[i] = r.Error != ""; window[i] {
+			errs++
+		}
+
+		n++
+		return n >= len(window) && float64(errs)/float64(len(window)) > c.Max
+	}
+}
+
+// String implements the StopCondition interface.
+func (c ErrorRatio) String() string {
+	return fmt.Sprintf("error ratio above %g in the last %d results", c.Max, max(c.Window, 1))
+}
+
+// ConsecutiveFailures is a StopCondition met when the last N Results all
+// have errors.
+type ConsecutiveFailures struct {
+	N int
+}
+
+// ConsecutiveFailures satisfies the StopCondition interface.
+var _ StopCondition = ConsecutiveFailures{}
+
+// Check implements the StopCondition interface.
+func (c ConsecutiveFailures) Check() func(*Result) bool {
+	n := 0
+	return func(r *Result) bool {
+		if r.Error == "" {
+			n = 0
+			return false
+		}
+		n++
+		return n >= c.N
+	}
+}
+
+// String implements the StopCondition interface.
+func (c ConsecutiveFailures) String() string {
+	return fmt.Sprintf("%d consecutive failures", c.N)
+}
+
+// latencyCheckEvery is the number of Results after which the quantile of a
+// LatencyCeiling is checked again, since computing it is relatively costly.
+const latencyCheckEvery = 100
+
+// LatencyCeiling is a StopCondition met when the given Quantile of the
+// latencies of all Results so far exceeds Max, once there are at least
+// MinSamples of them. The quantile is checked at MinSamples Results and then
+// every 100 more.
+type LatencyCeiling struct {
+	Quantile   float64 // Between 0 and 1, e.g. 0.99
+	Max        time.Duration
+	MinSamples int
+}
+
+// LatencyCeiling satisfies the StopCondition interface.
+var _ StopCondition = LatencyCeiling{}
+
+// Check implements the StopCondition interface.
+func (c LatencyCeiling) Check() func(*Result) bool {
+	var (
+		lats LatencyMetrics
+		n    int
+	)
+
+	return func(r *Result) bool {
+		lats.Add(r.Latency)
+		n++
+		return n >= c.MinSamples && (n-c.MinSamples)%latencyCheckEvery == 0 &&
+			lats.Quantile(c.Quantile) > c.Max
+	}
+}
+
+// String implements the StopCondition interface.
+func (c LatencyCeiling) String() string {
+	return fmt.Sprintf("%g quantile latency above %s", c.Quantile, c.Max)
+}
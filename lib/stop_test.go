@@ -0,0 +1,111 @@
+package vegeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStopConditions(t *testing.T) {
+	t.Parallel()
+
+	ok, fail := &Result{Latency: time.Millisecond}, &Result{Error: "500 Internal Server Error", Latency: time.Second}
+
+	for _, tc := range []struct {
+		cond    StopCondition
+		results []*Result
+		want    int // Index of the Result with which the condition is met, or -1.
+	}{
+		{ErrorRatio{Max: 0.5, Window: 4}, []*Result{fail, fail, fail}, -1},
+		{ErrorRatio{Max: 0.5, Window: 4}, []*Result{fail, fail, fail, ok}, 3},
+		{ErrorRatio{Max: 0.5, Window: 4}, []*Result{fail, ok, fail, ok, ok, fail, ok, fail}, -1},
+		{ErrorRatio{Max: 0.5, Window: 4}, []*Result{ok, ok, ok, ok, fail, fail, fail}, 6},
+		{ErrorRatio{Max: 0, Window: 1}, []*Result{ok, ok, fail}, 2},
+		{ConsecutiveFailures{N: 2}, []*Result{fail, ok, fail, ok}, -1},
+		{ConsecutiveFailures{N: 2}, []*Result{fail, ok, fail, fail}, 3},
+		{LatencyCeiling{Quantile: 0.5, Max: 10 * time.Millisecond, MinSamples: 3}, []*Result{fail, fail}, -1},
+		{LatencyCeiling{Quantile: 0.5, Max: 10 * time.Millisecond, MinSamples: 3}, []*Result{fail, fail, fail}, 2},
+		{LatencyCeiling{Quantile: 0.99, Max: 10 * time.Millisecond, MinSamples: 3}, []*Result{ok, ok, fail}, 2},
+		// The quantile is only checked every 100 Results after MinSamples.
+		{LatencyCeiling{Quantile: 0.5, Max: 10 * time.Millisecond, MinSamples: 1}, append([]*Result{ok}, repeat(fail, 100)...), 100},
+		{LatencyCeiling{Quantile: 0.5, Max: 10 * time.Millisecond, MinSamples: 1}, []*Result{ok, ok, ok, ok, ok}, -1},
+	} {
+		check, got := tc.cond.Check(), -1
+		for i, r := range tc.results {
+			if check(r) {
+				got = i
+				break
+			}
+		}
+
+		if got != tc.want {
+			t.Errorf("%s: met with result %d, want %d", tc.cond, got, tc.want)
+		}
+	}
+}
+
+func repeat(r *Result, n int) []*Result {
+	rs := make([]*Result, n)
+	for i := range rs {
+		rs[i] = r
+	}
+	return rs
+}
+
+func TestStopOn(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	cond := ConsecutiveFailures{N: 5}
+	atk := NewAttacker(StopOn(ErrorRatio{Max: 0.5, Window: 1000}, cond))
+
+	// Another attack of the same Attacker against a healthy server isn't
+	// stopped by the first one's condition.
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+
+	other := atk.Attack(NewStaticTargeter(Target{Method: "GET", URL: healthy.URL}), Rate{Freq: 100, Per: time.Second}, time.Second, "")
+
+	began, hits := time.Now(), 0
+	res := atk.Attack(tr, Rate{Freq: 100, Per: time.Second}, 10*time.Second, "")
+	for range res {
+		hits++
+	}
+
+	if took := time.Since(began); took > 2*time.Second {
+		t.Errorf("attack took %s, want it stopped early", took)
+	}
+
+	if hits < cond.N {
+		t.Errorf("got %d hits, want at least %d", hits, cond.N)
+	}
+
+	if got := atk.Tripped(res); got != cond {
+		t.Errorf("got tripped condition %v, want %v", got, cond)
+	}
+
+	if got := atk.Tripped(res); got != nil {
+		t.Errorf("got tripped condition %v again, want it forgotten", got)
+	}
+
+	otherHits := 0
+	for range other {
+		otherHits++
+	}
+
+	if otherHits < 90 {
+		t.Errorf("got %d hits of the other attack, want it to run to completion", otherHits)
+	}
+
+	if got := atk.Tripped(other); got != nil {
+		t.Errorf("got tripped condition %v of the other attack, want none", got)
+	}
+}
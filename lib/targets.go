@@ -10,9 +10,12 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
@@ -26,26 +29,77 @@ type Target struct {
 	URL    string      `json:"url"`
 	Body   []byte      `json:"body,omitempty"`
 	Header http.Header `json:"header,omitempty"`
+
+	// Timeout, Redirects and MaxBody override the Attacker's options of
+	// the same name for this Target when set.
+	Timeout   *time.Duration `json:"timeout,omitempty" jsonschema:"type=string,description=Go duration string (e.g. 5s) overriding the request timeout"`
+	Redirects *int           `json:"redirects,omitempty" jsonschema:"description=Number of redirects to follow overriding the attacker's (-1 does not follow but marks as success)"`
+	MaxBody   *int64         `json:"max_body,omitempty" jsonschema:"description=Maximum number of response body bytes to capture overriding the attacker's (-1 means no limit)"`
+
+	// Multipart, when set, replaces Body with a multipart/form-data body
+	// made of these parts, with a new boundary on every request.
+	Multipart []Part `json:"multipart,omitempty" jsonschema:"description=Parts of a multipart/form-data body built on every request in place of body"`
+
+	// BodyFile and BodyRandom, when set, replace Body with a body streamed on
+	// every request so that large bodies aren't held in memory: the content
+	// of the named file, or the given number of random bytes.
+	BodyFile   string `json:"body_file,omitempty" jsonschema:"description=Path of a file streamed as the request body in place of body"`
+	BodyRandom int64  `json:"body_random,omitempty" jsonschema:"description=Number of random bytes streamed as the request body in place of body"`
 }
 
 // Request creates an *http.Request out of Target and returns it along with an
 // error in case of failure.
 func (t *Target) Request() (*http.Request, error) {
-	var body io.Reader
-	if len(t.Body) != 0 {
+	var (
+		body        io.Reader
+		src         *bodySource
+		contentType string
+		err         error
+	)
+
+	switch {
+	case len(t.Multipart) != 0:
+		src, contentType, err = multipartBody(t.Multipart)
+	case t.BodyFile != "":
+		src, err = fileBody(t.BodyFile)
+	case t.BodyRandom > 0:
+		src = randomBody(t.BodyRandom)
+	case len(t.Body) != 0:
 		body = bytes.NewReader(t.Body)
 	}
 
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest(t.Method, t.URL, body)
 	if err != nil {
 		return nil, err
 	}
 
+	if src != nil && src.size > 0 {
+		if req.Body, err = src.open(); err != nil {
+			return nil, err
+		}
+		req.GetBody = src.open
+		req.ContentLength = src.size
+	}
+
 	for k, vs := range t.Header {
 		req.Header[k] = make([]string, len(vs))
 		copy(req.Header[k], vs)
 	}
 
+	if contentType != "" {
+		// The boundary is only known now, so any given content type is replaced.
+		for k := range req.Header {
+			if strings.EqualFold(k, "Content-Type") {
+				delete(req.Header, k)
+			}
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
 	if host := req.Header.Get("Host"); host != "" {
 		req.Host = host
 	}
@@ -64,7 +118,13 @@ func (t *Target) Equal(other *Target) bool {
 		equal := t.Method == other.Method &&
 			t.URL == other.URL &&
 			bytes.Equal(t.Body, other.Body) &&
-			len(t.Header) == len(other.Header)
+			len(t.Header) == len(other.Header) &&
+			equalPtr(t.Timeout, other.Timeout) &&
+			equalPtr(t.Redirects, other.Redirects) &&
+			equalPtr(t.MaxBody, other.MaxBody) &&
+			slices.Equal(t.Multipart, other.Multipart) &&
+			t.BodyFile == other.BodyFile &&
+			t.BodyRandom == other.BodyRandom
 
 		if !equal {
 			return false
@@ -86,6 +146,14 @@ func (t *Target) Equal(other *Target) bool {
 	}
 }
 
+// equalPtr returns true if both pointers are nil or point to equal values.
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 var (
 	// ErrNoTargets is returned when not enough Targets are available.
 	ErrNoTargets = errors.New("no targets to attack")
@@ -99,7 +167,7 @@ var (
 	ErrNoURL = errors.New("target: required url is missing")
 	// TargetFormats contains the canonical list of the valid target
 	// format identifiers.
-	TargetFormats = []string{HTTPTargetFormat, JSONTargetFormat}
+	TargetFormats = []string{HTTPTargetFormat, JSONTargetFormat, HARTargetFormat, CurlTargetFormat, AccessLogTargetFormat}
 )
 
 const (
@@ -126,6 +194,10 @@ func (tr Targeter) Decode(t *Target) error {
 //
 //	{"method":"POST", "url":"https://goku/1", "header":{"Content-Type":["text/plain"], "body": "Rk9P"}
 //	{"method":"GET",  "url":"https://goku/2"}
+//	{"method":"GET",  "url":"https://goku/3", "timeout":"5m", "redirects":-1, "max_body":1024}
+//
+// The optional timeout, redirects and max_body fields override the Attacker's
+// options of the same name for that target.
 //
 // body will be set as the Target's body if no body is provided in each target definition.
 // hdr will be merged with the each Target's headers.
@@ -172,6 +244,12 @@ func NewJSONTargeter(src io.Reader, body []byte, header http.Header) Targeter {
 
 		tgt.Method = t.Method
 		tgt.URL = t.URL
+		tgt.Timeout = t.Timeout
+		tgt.Redirects = t.Redirects
+		tgt.MaxBody = t.MaxBody
+		tgt.Multipart = t.Multipart
+		tgt.BodyFile = t.BodyFile
+		tgt.BodyRandom = t.BodyRandom
 		if tgt.Body = body; len(t.Body) > 0 {
 			tgt.Body = t.Body
 		}
@@ -256,6 +334,23 @@ func ReadAllTargets(t Targeter) (tgts []Target, err error) {
 //
 //	POST https://foo.bar/b/c/a
 //	Header-X: 123
+//	@timeout: 5m
+//	@redirects: -1
+//	@max-body: 1024
+//
+//	POST https://foo.bar/upload
+//	@form: title=vacation
+//	@form: photo=@/path/to/photo.png;type=image/png
+//
+//	PUT https://foo.bar/blobs/1
+//	@body-file: /path/to/large/file
+//
+// The @timeout, @redirects and @max-body directives override the Attacker's
+// options of the same name for that target. The @form directives, given in
+// the syntax of curl's -F option, declare the parts of a multipart/form-data
+// body built on every request. The @body-file and @body-random directives
+// declare a body streamed on every request out of a file or a number of random
+// bytes, rather than read in memory like @/path/to/body/file.
 //
 // body will be set as the Target's body if no body is provided.
 // hdr will be merged with the each Target's headers.
@@ -284,6 +379,8 @@ func NewHTTPTargeter(src io.Reader, body []byte, hdr http.Header) Targeter {
 
 		tgt.Body = body
 		tgt.Header = http.Header{}
+		tgt.Timeout, tgt.Redirects, tgt.MaxBody = nil, nil, nil
+		tgt.Multipart, tgt.BodyFile, tgt.BodyRandom = nil, "", 0
 		for k, vs := range hdr {
 			tgt.Header[k] = vs
 		}
@@ -309,6 +406,11 @@ func NewHTTPTargeter(src io.Reader, body []byte, hdr http.Header) Targeter {
 				break
 			} else if strings.HasPrefix(line, "#") {
 				continue
+			} else if ok, err := parseDirective(tgt, line); ok || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
 			} else if strings.HasPrefix(line, "@") {
 				if tgt.Body, err = os.ReadFile(line[1:]); err != nil {
 					return fmt.Errorf("bad body: %w", err)
@@ -336,6 +438,58 @@ func NewHTTPTargeter(src io.Reader, body []byte, hdr http.Header) Targeter {
 	}
 }
 
+// parseDirective parses a target directive line such as "@timeout: 5s" into
+// the given Target. It returns false if the line isn't a known directive.
+func parseDirective(tgt *Target, line string) (bool, error) {
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return false, nil
+	}
+
+	value = strings.TrimSpace(value)
+	switch strings.TrimSpace(name) {
+	case "@timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("bad timeout directive: %w", err)
+		}
+		tgt.Timeout = &d
+	case "@redirects":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("bad redirects directive: %w", err)
+		}
+		tgt.Redirects = &n
+	case "@max-body":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("bad max-body directive: %w", err)
+		}
+		tgt.MaxBody = &n
+	case "@body-file":
+		if value == "" {
+			return true, fmt.Errorf("bad body-file directive: no file")
+		}
+		tgt.BodyFile = value
+	case "@body-random":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return true, fmt.Errorf("bad body-random directive: %q", value)
+		}
+		tgt.BodyRandom = n
+	case "@form":
+		p, err := ParsePart(value)
+		if err != nil {
+			return true, fmt.Errorf("bad form directive: %w", err)
+		}
+		tgt.Multipart = append(tgt.Multipart, p)
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
 var httpMethodChecker = regexp.MustCompile(`^[A-Z]+\s`)
 
 // A line starts with an http method when the first word is uppercase ascii
@@ -5,6 +5,7 @@ package vegeta
 
 import (
 	http "net/http"
+	time "time"
 
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
@@ -83,6 +84,32 @@ func (t *jsonTarget) decode(in *jlexer.Lexer) {
 				}
 				in.Delim('}')
 			}
+		case "timeout":
+			d, err := time.ParseDuration(in.String())
+			if err != nil {
+				in.AddError(err)
+			}
+			t.Timeout = &d
+		case "redirects":
+			n := in.Int()
+			t.Redirects = &n
+		case "max_body":
+			n := in.Int64()
+			t.MaxBody = &n
+		case "body_file":
+			t.BodyFile = string(in.String())
+		case "body_random":
+			t.BodyRandom = in.Int64()
+		case "multipart":
+			in.Delim('[')
+			t.Multipart = nil
+			for !in.IsDelim(']') {
+				var p Part
+				decodePart(in, &p)
+				t.Multipart = append(t.Multipart, p)
+				in.WantComma()
+			}
+			in.Delim(']')
 		default:
 			in.SkipRecursive()
 		}
@@ -163,5 +190,122 @@ func (t jsonTarget) encode(out *jwriter.Writer) {
 			out.RawByte('}')
 		}
 	}
+	if t.Timeout != nil {
+		const prefix string = ",\"timeout\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(t.Timeout.String())
+	}
+	if t.Redirects != nil {
+		const prefix string = ",\"redirects\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(*t.Redirects)
+	}
+	if t.MaxBody != nil {
+		const prefix string = ",\"max_body\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int64(*t.MaxBody)
+	}
+	if len(t.Multipart) != 0 {
+		const prefix string = ",\"multipart\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for i := range t.Multipart {
+			if i > 0 {
+				out.RawByte(',')
+			}
+			encodePart(out, &t.Multipart[i])
+		}
+		out.RawByte(']')
+	}
+	if t.BodyFile != "" {
+		const prefix string = ",\"body_file\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(t.BodyFile)
+	}
+	if t.BodyRandom != 0 {
+		const prefix string = ",\"body_random\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int64(t.BodyRandom)
+	}
+	out.RawByte('}')
+}
+
+func decodePart(in *jlexer.Lexer, p *Part) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			p.Name = string(in.String())
+		case "value":
+			p.Value = string(in.String())
+		case "file":
+			p.File = string(in.String())
+		case "filename":
+			p.Filename = string(in.String())
+		case "content_type":
+			p.ContentType = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+func encodePart(out *jwriter.Writer, p *Part) {
+	out.RawString("{\"name\":")
+	out.String(p.Name)
+	for _, f := range []struct{ key, value string }{
+		{",\"value\":", p.Value},
+		{",\"file\":", p.File},
+		{",\"filename\":", p.Filename},
+		{",\"content_type\":", p.ContentType},
+	} {
+		if f.value != "" {
+			out.RawString(f.key)
+			out.String(f.value)
+		}
+	}
 	out.RawByte('}')
 }
@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestTargetRequest(t *testing.T) {
@@ -90,6 +94,149 @@ func TestTargetRequest_EmptyBody(t *testing.T) {
 	}
 }
 
+func TestTargetRequest_Multipart(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.CreateTemp("", "vegeta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+	f.WriteString("PNG!")
+
+	tgt := Target{
+		Method: "POST",
+		URL:    "http://:9999/upload",
+		Body:   []byte("ignored"),
+		Header: http.Header{"content-type": []string{"text/plain"}},
+		Multipart: []Part{
+			{Name: "title", Value: "vacation"},
+			{Name: "photo", File: f.Name(), ContentType: "image/png"},
+			{Name: "raw", File: f.Name(), Filename: "raw.bin"},
+		},
+	}
+
+	boundaries := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		req, err := tgt.Request()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := req.Header["content-type"]; ok {
+			t.Fatal("given content type wasn't replaced")
+		}
+
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		boundaries[req.Header.Get("Content-Type")] = true
+
+		if got := req.MultipartForm.Value["title"]; !reflect.DeepEqual(got, []string{"vacation"}) {
+			t.Errorf("got title %v, want vacation", got)
+		}
+
+		for name, want := range map[string][2]string{
+			"photo": {filepath.Base(f.Name()), "image/png"},
+			"raw":   {"raw.bin", "application/octet-stream"},
+		} {
+			fh := req.MultipartForm.File[name][0]
+			if fh.Filename != want[0] || fh.Header.Get("Content-Type") != want[1] {
+				t.Errorf("%s: got filename %q and content type %q, want %q", name, fh.Filename, fh.Header.Get("Content-Type"), want)
+			}
+
+			ff, _ := fh.Open()
+			if data, _ := io.ReadAll(ff); string(data) != "PNG!" {
+				t.Errorf("%s: got content %q, want PNG!", name, data)
+			}
+		}
+	}
+
+	if len(boundaries) != 2 {
+		t.Errorf("want a new boundary on every request, got %v", boundaries)
+	}
+
+	tgt.Multipart = []Part{{Name: "missing", File: "/does/not/exist"}}
+	if _, err := tgt.Request(); err == nil {
+		t.Error("want error with missing file")
+	}
+}
+
+func TestTargetRequest_StreamedBody(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("Kamehameha!"), 1<<16)
+	f, err := os.CreateTemp("", "vegeta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+	f.Write(data)
+
+	// Redirects with a 307 resend the body, read again with GetBody.
+	var received [][]byte
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		mu.Lock()
+		received = append(received, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name string
+		tgt  Target
+		want []byte
+	}{
+		{name: "file", tgt: Target{BodyFile: f.Name(), Body: []byte("ignored")}, want: data},
+		{name: "random", tgt: Target{BodyRandom: 100_003}},
+	} {
+		tc.tgt.Method, tc.tgt.URL = "PUT", server.URL+"/redirect"
+		req, err := tc.tgt.Request()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		size := int64(len(tc.want))
+		if tc.want == nil {
+			size = tc.tgt.BodyRandom
+		}
+
+		if req.ContentLength != size || req.GetBody == nil {
+			t.Fatalf("%s: got content length %d and GetBody %v, want %d", tc.name, req.ContentLength, req.GetBody != nil, size)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		res.Body.Close()
+
+		mu.Lock()
+		got := received[len(received)-1]
+		mu.Unlock()
+
+		if int64(len(got)) != size {
+			t.Errorf("%s: got body of %d bytes, want %d", tc.name, len(got), size)
+		} else if tc.want != nil && !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got a different body than the file's", tc.name)
+		}
+	}
+
+	tgt := Target{Method: "PUT", URL: server.URL, BodyFile: "/does/not/exist"}
+	if _, err := tgt.Request(); err == nil {
+		t.Error("want error with missing body file")
+	}
+}
+
 func TestJSONTargeter(t *testing.T) {
 	target := func(s string) io.Reader {
 		return strings.NewReader(s + "\n")
@@ -173,6 +320,19 @@ func TestJSONTargeter(t *testing.T) {
 			in:   &Target{},
 			out:  &Target{Method: "GET", URL: "http://goku", Header: http.Header{"x": []string{"foo"}}, Body: []byte("ATTACK!")},
 		},
+		{
+			name: "overrides",
+			src:  target(`{"method": "GET", "url": "http://goku", "timeout": "1m30s", "redirects": -1, "max_body": 1024}`),
+			in:   &Target{},
+			out:  &Target{Method: "GET", URL: "http://goku", Timeout: ptr(90 * time.Second), Redirects: ptr(-1), MaxBody: ptr[int64](1024)},
+		},
+		{
+			name: "bad timeout",
+			src:  target(`{"method": "GET", "url": "http://goku", "timeout": "soon"}`),
+			in:   &Target{},
+			out:  &Target{},
+			err:  errors.New(`time: invalid duration "soon"`),
+		},
 		{
 			name: "skips empty lines and surrounding whitespace",
 			src: strings.NewReader(`
@@ -305,6 +465,24 @@ func TestNewHTTPTargeter(t *testing.T) {
 		errors.New("bad header"): `
 			GET http://:6060
 			: 1234`,
+		errors.New("bad timeout directive"): `
+			GET http://:6060
+			@timeout: soon`,
+		errors.New("bad redirects directive"): `
+			GET http://:6060
+			@redirects: many`,
+		errors.New("bad max-body directive"): `
+			GET http://:6060
+			@max-body: 1KB`,
+		errors.New("bad body-random directive"): `
+			PUT http://:6060
+			@body-random: 1GB`,
+		errors.New("bad body-file directive"): `
+			PUT http://:6060
+			@body-file:`,
+		errors.New("bad form directive"): `
+			POST http://:6060
+			@form: photo=@`,
 	} {
 		src := bytes.NewBufferString(strings.TrimSpace(def))
 		read := NewHTTPTargeter(src, []byte{}, http.Header{})
@@ -354,6 +532,26 @@ func TestNewHTTPTargeter(t *testing.T) {
 		X-Header: 1
 		# Another comment.
 		X-Header: 2`,
+		`
+
+		POST http://foobar.org/export
+		@timeout: 5m
+		@redirects: -1
+		@max-body: 0
+		X-Header: 1
+		@`, bodyf.Name(),
+		`
+
+		POST http://foobar.org/upload
+		@form: title=vacation
+		@form: photo=@`, bodyf.Name(), `;type=text/plain;filename=photo.txt`,
+		`
+
+		PUT http://foobar.org/blobs/1
+		@body-file: /path/to/large/file
+
+		PUT http://foobar.org/blobs/2
+		@body-random: 1048576`,
 	)
 
 	src := bytes.NewBufferString(strings.TrimSpace(targets))
@@ -422,6 +620,42 @@ func TestNewHTTPTargeter(t *testing.T) {
 				"Content-Type": []string{"text/plain"},
 			},
 		},
+		{
+			Method: "POST",
+			URL:    "http://foobar.org/export",
+			Body:   []byte("Hello world!"),
+			Header: http.Header{
+				"X-Header":     []string{"1"},
+				"Content-Type": []string{"text/plain"},
+			},
+			Timeout:   ptr(5 * time.Minute),
+			Redirects: ptr(-1),
+			MaxBody:   ptr[int64](0),
+		},
+		{
+			Method: "POST",
+			URL:    "http://foobar.org/upload",
+			Body:   []byte{},
+			Header: http.Header{"Content-Type": []string{"text/plain"}},
+			Multipart: []Part{
+				{Name: "title", Value: "vacation"},
+				{Name: "photo", File: bodyf.Name(), Filename: "photo.txt", ContentType: "text/plain"},
+			},
+		},
+		{
+			Method:   "PUT",
+			URL:      "http://foobar.org/blobs/1",
+			Body:     []byte{},
+			Header:   http.Header{"Content-Type": []string{"text/plain"}},
+			BodyFile: "/path/to/large/file",
+		},
+		{
+			Method:     "PUT",
+			URL:        "http://foobar.org/blobs/2",
+			Body:       []byte{},
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			BodyRandom: 1 << 20,
+		},
 	} {
 		var got Target
 		if err := read(&got); err != nil {
@@ -438,6 +672,58 @@ func TestNewHTTPTargeter(t *testing.T) {
 	}
 }
 
+func TestJSONTargetEncoder(t *testing.T) {
+	t.Parallel()
+
+	want := []Target{
+		{Method: "GET", URL: "http://goku"},
+		{
+			Method:    "POST",
+			URL:       "http://goku/export",
+			Body:      []byte("BIG BANG!"),
+			Header:    http.Header{"Content-Type": []string{"high/energy"}},
+			Timeout:   ptr(time.Minute),
+			Redirects: ptr(0),
+			MaxBody:   ptr[int64](-1),
+		},
+		{
+			Method: "POST",
+			URL:    "http://goku/upload",
+			Multipart: []Part{
+				{Name: "title", Value: "kamehameha"},
+				{Name: "video", File: "/tmp/kamehameha.mp4", Filename: "k.mp4", ContentType: "video/mp4"},
+			},
+		},
+		{Method: "PUT", URL: "http://goku/blobs/1", BodyFile: "/tmp/blob"},
+		{Method: "PUT", URL: "http://goku/blobs/2", BodyRandom: 500 << 20},
+	}
+
+	var buf bytes.Buffer
+	enc := NewJSONTargetEncoder(&buf)
+	for i := range want {
+		if err := enc.Encode(&want[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ReadAllTargets(NewJSONTargeter(&buf, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d targets, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if !got[i].Equal(&want[i]) {
+			t.Errorf("got Target %#v, want %#v", got[i], want[i])
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
 func TestErrNilTarget(t *testing.T) {
 	t.Parallel()
 
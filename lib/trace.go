@@ -0,0 +1,45 @@
+package vegeta
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+)
+
+// TraceContext returns a functional option which makes the Attacker inject
+// a W3C Trace Context traceparent header into every request, overriding any
+// set by its Target, with a new random trace ID and client span ID which are
+// stored in its Result. The requests can then be correlated with their server
+// side traces, whose root spans are the client spans with the Result's SpanID.
+func TraceContext(enabled bool) func(*Attacker) {
+	return func(a *Attacker) { a.traceContext = enabled }
+}
+
+// newTraceID returns a new random, hex encoded trace ID, which is regenerated
+// if all zeros since that's an invalid trace ID.
+func newTraceID() string {
+	var id [16]byte
+	for hi, lo := uint64(0), uint64(0); hi == 0 && lo == 0; {
+		hi, lo = rand.Uint64(), rand.Uint64()
+		binary.BigEndian.PutUint64(id[:8], hi)
+		binary.BigEndian.PutUint64(id[8:], lo)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// newSpanID returns a new random, hex encoded span ID, which is regenerated
+// if all zeros since that's an invalid span ID.
+func newSpanID() string {
+	var id [8]byte
+	for v := uint64(0); v == 0; {
+		v = rand.Uint64()
+		binary.BigEndian.PutUint64(id[:], v)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// traceParent returns the value of the traceparent header of the given
+// Result's sampled client span.
+func traceParent(r *Result) string {
+	return "00-" + r.TraceID + "-" + r.SpanID + "-01"
+}
@@ -0,0 +1,141 @@
+package vegeta
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IDPlaceholder replaces the IDs detected by a URLNormalizer.
+const IDPlaceholder = ":id"
+
+// A URLRule rewrites the parts of URLs matched by its Pattern with its
+// Template, such as /users/\d+ with /users/:id.
+type URLRule struct {
+	Pattern *regexp.Regexp
+	// Template may refer to the Pattern's submatches, as in
+	// regexp.Regexp.Expand.
+	Template string
+}
+
+// URLNormalizer normalizes URLs into templates, so that metrics of URLs with
+// IDs, such as those of templated targets, can be aggregated with bounded
+// cardinality. It's safe for concurrent use.
+type URLNormalizer struct {
+	// Rules rewrite URLs, in order.
+	Rules []URLRule
+	// DetectIDs makes path segments and query parameter values which look
+	// like IDs, such as numbers, UUIDs, and long hex or alphanumeric tokens,
+	// be replaced with the IDPlaceholder after applying the Rules.
+	DetectIDs bool
+	// Max is the maximum number of distinct normalized URLs, beyond which
+	// further ones are normalized to the OtherGroup. Zero means no maximum.
+	Max int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// Normalize returns the normalized URL of the given one.
+func (n *URLNormalizer) Normalize(u string) string {
+	for _, r := range n.Rules {
+		u = r.Pattern.ReplaceAllString(u, r.Template)
+	}
+
+	if n.DetectIDs {
+		u = replaceIDs(u)
+	}
+
+	if n.Max <= 0 {
+		return u
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.seen[u]; ok {
+		return u
+	}
+
+	if len(n.seen) >= n.Max {
+		return OtherGroup
+	}
+
+	if n.seen == nil {
+		n.seen = map[string]struct{}{}
+	}
+	n.seen[u] = struct{}{}
+
+	return u
+}
+
+// GroupByNormalizedURL returns a GroupKey which groups Results by their
+// request URL normalized with the given URLNormalizer.
+func GroupByNormalizedURL(n *URLNormalizer) GroupKey {
+	return func(r *Result) string { return n.Normalize(r.URL) }
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isID returns true if the given path segment or query parameter value looks
+// like an ID: a number, a UUID, or a token of at least 16 letters and
+// digits with at least one of each, such as a hash, ObjectID or ULID.
+func isID(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	if uuidRe.MatchString(s) {
+		return true
+	}
+
+	var letters, digits int
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letters++
+		default:
+			return false
+		}
+	}
+
+	return letters == 0 || (len(s) >= 16 && digits > 0)
+}
+
+// replaceIDs replaces the path segments and query parameter values of the
+// given URL which look like IDs with the IDPlaceholder. URLs which can't be
+// parsed are returned as is.
+func replaceIDs(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segments {
+		if isID(seg) {
+			segments[i] = IDPlaceholder
+		}
+	}
+
+	rawPath := strings.Join(segments, "/")
+	if u.Path, err = url.PathUnescape(rawPath); err != nil {
+		return s
+	}
+	u.RawPath = rawPath
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, p := range params {
+			if k, v, ok := strings.Cut(p, "="); ok && isID(v) {
+				params[i] = k + "=" + IDPlaceholder
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String()
+}
@@ -0,0 +1,103 @@
+package vegeta
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestURLNormalizer(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		n    *URLNormalizer
+		in   string
+		want string
+	}{
+		{
+			name: "none",
+			n:    &URLNormalizer{},
+			in:   "http://goku/users/42",
+			want: "http://goku/users/42",
+		},
+		{
+			name: "rule",
+			n: &URLNormalizer{Rules: []URLRule{
+				{regexp.MustCompile(`/users/\d+`), "/users/:id"},
+				{regexp.MustCompile(`/orders/([a-z]+)-\d+`), "/orders/$1-:n"},
+			}},
+			in:   "http://goku/users/42/orders/eu-7",
+			want: "http://goku/users/:id/orders/eu-:n",
+		},
+		{
+			name: "numbers",
+			n:    &URLNormalizer{DetectIDs: true},
+			in:   "http://goku/v1/users/42/posts/7?page=3&sort=asc",
+			want: "http://goku/v1/users/:id/posts/:id?page=:id&sort=asc",
+		},
+		{
+			name: "uuids and tokens",
+			n:    &URLNormalizer{DetectIDs: true},
+			in:   "http://goku/carts/123e4567-e89b-12d3-a456-426614174000/items/507f1f77bcf86cd799439011/01ARZ3NDEKTSV4RRFFQ69G5FAV/checkout",
+			want: "http://goku/carts/:id/items/:id/:id/checkout",
+		},
+		{
+			name: "words",
+			n:    &URLNormalizer{DetectIDs: true},
+			in:   "http://goku/api/v2/settings/notifications/",
+			want: "http://goku/api/v2/settings/notifications/",
+		},
+		{
+			name: "escaped path",
+			n:    &URLNormalizer{DetectIDs: true},
+			in:   "http://goku/files/a%2Fb/9",
+			want: "http://goku/files/a%2Fb/:id",
+		},
+		{
+			name: "rules then detection",
+			n: &URLNormalizer{
+				Rules:     []URLRule{{regexp.MustCompile(`/u/[^/]+`), "/u/:user"}},
+				DetectIDs: true,
+			},
+			in:   "http://goku/u/goku/7",
+			want: "http://goku/u/:user/:id",
+		},
+	} {
+		if got := tc.n.Normalize(tc.in); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestURLNormalizerMax(t *testing.T) {
+	t.Parallel()
+
+	n := URLNormalizer{DetectIDs: true, Max: 2}
+	for _, tc := range []struct{ in, want string }{
+		{"http://goku/users/1", "http://goku/users/:id"},
+		{"http://goku/orders/2", "http://goku/orders/:id"},
+		{"http://goku/carts/3", OtherGroup},
+		{"http://goku/users/4", "http://goku/users/:id"},
+	} {
+		if got := n.Normalize(tc.in); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGroupByNormalizedURL(t *testing.T) {
+	t.Parallel()
+
+	g := NewGroups(GroupByNormalizedURL(&URLNormalizer{DetectIDs: true}), 0, func() Report { return &Metrics{} })
+	for _, u := range []string{"http://goku/users/1", "http://goku/users/2", "http://goku/"} {
+		g.Add(&Result{URL: u})
+	}
+
+	if got, want := g.Keys(), []string{"http://goku/", "http://goku/users/:id"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got keys %q, want %q", got, want)
+	}
+
+	if got := g.Reports["http://goku/users/:id"].(*Metrics).Requests; got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
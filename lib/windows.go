@@ -0,0 +1,237 @@
+package vegeta
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Windows is a Report which buckets Results by their Timestamp into fixed
+// time windows of an attack, with Metrics for each. It can be reported on
+// while Results are still being added, such as live during an attack, in
+// which case a window is complete and reported once a Result at least one
+// window later arrives. Results which arrive after their window was reported
+// are dropped and counted as Late.
+type Windows struct {
+	// Size is the duration of each window.
+	Size time.Duration
+	// Late is the number of dropped Results which arrived after their
+	// window was reported.
+	Late uint64
+
+	windows map[int64]*Metrics // By window start in Unix nanoseconds.
+	first   time.Time          // Start of the earliest window.
+	last    time.Time          // Start of the latest window.
+	next    time.Time          // Start of the next window to report.
+	latest  time.Time          // Latest Result timestamp.
+	closed  bool
+}
+
+// WindowMetrics holds metrics computed out of the Results of a time window.
+type WindowMetrics struct {
+	// Start is the beginning of the window.
+	Start time.Time `json:"start"`
+	// End is the end of the window.
+	End time.Time `json:"end"`
+	// Requests is the number of requests sent in the window.
+	Requests uint64 `json:"requests"`
+	// Rate is the rate of requests sent per second in the window.
+	Rate float64 `json:"rate"`
+	// Throughput is the rate of successful requests per second in the window.
+	Throughput float64 `json:"throughput"`
+	// Success is the ratio of non-error responses in the window.
+	Success float64 `json:"success"`
+	// Latencies holds the request latency metrics of the window.
+	Latencies LatencyMetrics `json:"latencies"`
+	// BytesIn is the rate of incoming bytes per second in the window.
+	BytesIn float64 `json:"bytes_in_rate"`
+	// BytesOut is the rate of outgoing bytes per second in the window.
+	BytesOut float64 `json:"bytes_out_rate"`
+}
+
+// NewWindows returns new Windows of the given size.
+func NewWindows(size time.Duration) *Windows {
+	return &Windows{Size: size}
+}
+
+// Add implements the Add method of the Report interface by adding the given
+// Result to the Metrics of its window.
+func (w *Windows) Add(r *Result) {
+	if w.windows == nil {
+		w.windows = map[int64]*Metrics{}
+	}
+
+	start := r.Timestamp.Truncate(w.Size)
+	if !w.next.IsZero() && start.Before(w.next) {
+		w.Late++
+		return
+	}
+
+	m, ok := w.windows[start.UnixNano()]
+	if !ok {
+		m = &Metrics{}
+		w.windows[start.UnixNano()] = m
+	}
+	m.Add(r)
+
+	if w.first.IsZero() || start.Before(w.first) {
+		w.first = start
+	}
+
+	if start.After(w.last) {
+		w.last = start
+	}
+
+	if r.Timestamp.After(w.latest) {
+		w.latest = r.Timestamp
+	}
+}
+
+// Close implements the Close method of the Report interface by marking all
+// the windows as complete.
+func (w *Windows) Close() {
+	w.closed = true
+}
+
+// Complete returns the metrics of the windows which are complete and weren't
+// returned before, in order, including the ones without Results.
+func (w *Windows) Complete() []WindowMetrics {
+	if len(w.windows) == 0 && w.next.IsZero() {
+		return nil
+	}
+
+	if w.next.IsZero() {
+		w.next = w.first
+	}
+
+	var ws []WindowMetrics
+	for {
+		end := w.next.Add(w.Size)
+		if w.closed && w.next.After(w.last) || !w.closed && w.latest.Before(end.Add(w.Size)) {
+			return ws
+		}
+
+		m := w.windows[w.next.UnixNano()]
+		if m == nil {
+			m = &Metrics{}
+		}
+		m.Close()
+		delete(w.windows, w.next.UnixNano())
+
+		secs := w.Size.Seconds()
+		ws = append(ws, WindowMetrics{
+			Start:      w.next,
+			End:        end,
+			Requests:   m.Requests,
+			Rate:       float64(m.Requests) / secs,
+			Throughput: float64(m.success) / secs,
+			Success:    m.Success,
+			Latencies:  m.Latencies,
+			BytesIn:    float64(m.BytesIn.Total) / secs,
+			BytesOut:   float64(m.BytesOut.Total) / secs,
+		})
+
+		w.next = end
+	}
+}
+
+// NewWindowTextReporter returns a Reporter that writes out the complete
+// Windows as rows of a formatted text table, preceded by its header on the
+// first call.
+func NewWindowTextReporter(w *Windows) Reporter {
+	const (
+		header = "%-25s  %8s  %9s  %10s  %8s  %9s  %9s  %9s  %9s  %9s  %9s  %12s  %12s\n"
+		row    = "%-25s  %8d  %9.2f  %10.2f  %7.2f%%  %9s  %9s  %9s  %9s  %9s  %9s  %12.2f  %12.2f\n"
+	)
+
+	wroteHeader := false
+	return func(out io.Writer) error {
+		if !wroteHeader {
+			if _, err := fmt.Fprintf(out, header, "Start", "Requests", "Rate", "Throughput", "Success",
+				"Mean", "50", "90", "95", "99", "Max", "Bytes In/s", "Bytes Out/s"); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+
+		for _, m := range w.Complete() {
+			if _, err := fmt.Fprintf(out, row, m.Start.Format(time.RFC3339),
+				m.Requests, m.Rate, m.Throughput, m.Success*100,
+				RoundDuration(m.Latencies.Mean),
+				RoundDuration(m.Latencies.P50),
+				RoundDuration(m.Latencies.P90),
+				RoundDuration(m.Latencies.P95),
+				RoundDuration(m.Latencies.P99),
+				RoundDuration(m.Latencies.Max),
+				m.BytesIn, m.BytesOut,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// NewWindowCSVReporter returns a Reporter that writes out the complete
+// Windows as CSV records, preceded by a header record on the first call.
+// Latencies are in nanoseconds.
+func NewWindowCSVReporter(w *Windows) Reporter {
+	wroteHeader := false
+	return func(out io.Writer) error {
+		cw := csv.NewWriter(out)
+		if !wroteHeader {
+			if err := cw.Write([]string{
+				"start", "end", "requests", "rate", "throughput", "success",
+				"latency_min", "latency_mean", "latency_50th", "latency_90th",
+				"latency_95th", "latency_99th", "latency_max",
+				"bytes_in_rate", "bytes_out_rate",
+			}); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+
+		for _, m := range w.Complete() {
+			if err := cw.Write([]string{
+				m.Start.Format(time.RFC3339Nano),
+				m.End.Format(time.RFC3339Nano),
+				strconv.FormatUint(m.Requests, 10),
+				strconv.FormatFloat(m.Rate, 'f', -1, 64),
+				strconv.FormatFloat(m.Throughput, 'f', -1, 64),
+				strconv.FormatFloat(m.Success, 'f', -1, 64),
+				strconv.FormatInt(int64(m.Latencies.Min), 10),
+				strconv.FormatInt(int64(m.Latencies.Mean), 10),
+				strconv.FormatInt(int64(m.Latencies.P50), 10),
+				strconv.FormatInt(int64(m.Latencies.P90), 10),
+				strconv.FormatInt(int64(m.Latencies.P95), 10),
+				strconv.FormatInt(int64(m.Latencies.P99), 10),
+				strconv.FormatInt(int64(m.Latencies.Max), 10),
+				strconv.FormatFloat(m.BytesIn, 'f', -1, 64),
+				strconv.FormatFloat(m.BytesOut, 'f', -1, 64),
+			}); err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+// NewWindowJSONReporter returns a Reporter that writes out the complete
+// Windows as newline delimited JSON objects.
+func NewWindowJSONReporter(w *Windows) Reporter {
+	return func(out io.Writer) error {
+		enc := json.NewEncoder(out)
+		for _, m := range w.Complete() {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
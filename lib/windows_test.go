@@ -0,0 +1,143 @@
+package vegeta
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+
+	began := time.Unix(1000, 0).UTC()
+	at := func(d time.Duration, code uint16) *Result {
+		return &Result{Timestamp: began.Add(d), Code: code, Latency: 10 * time.Millisecond, BytesIn: 100}
+	}
+
+	w := NewWindows(time.Second)
+
+	w.Add(at(100*time.Millisecond, 200))
+	w.Add(at(900*time.Millisecond, 500))
+	if ws := w.Complete(); len(ws) != 0 {
+		t.Fatalf("got %d complete windows before a later result, want none", len(ws))
+	}
+
+	// Out of order results within the grace window end up in their window.
+	w.Add(at(1500*time.Millisecond, 200))
+	w.Add(at(500*time.Millisecond, 200))
+	w.Add(at(2*time.Second, 200))
+
+	ws := w.Complete()
+	if len(ws) != 1 {
+		t.Fatalf("got %d complete windows, want 1", len(ws))
+	}
+
+	want := WindowMetrics{
+		Start:      began,
+		End:        began.Add(time.Second),
+		Requests:   3,
+		Rate:       3,
+		Throughput: 2,
+		Success:    2.0 / 3,
+		BytesIn:    300,
+	}
+
+	got := ws[0]
+	got.Latencies = LatencyMetrics{}
+	if got != want {
+		t.Errorf("got window %+v, want %+v", got, want)
+	}
+
+	if ws[0].Latencies.P99 != 10*time.Millisecond {
+		t.Errorf("got p99 %s, want 10ms", ws[0].Latencies.P99)
+	}
+
+	// Results of reported windows are late.
+	w.Add(at(999*time.Millisecond, 200))
+	if w.Late != 1 {
+		t.Errorf("got %d late results, want 1", w.Late)
+	}
+
+	// Windows without results in between are reported too.
+	w.Add(at(4200*time.Millisecond, 200))
+	w.Close()
+
+	ws = w.Complete()
+	var starts []time.Duration
+	var requests []uint64
+	for _, m := range ws {
+		starts = append(starts, m.Start.Sub(began))
+		requests = append(requests, m.Requests)
+	}
+
+	if got, want := len(ws), 4; got != want {
+		t.Fatalf("got %d windows at %v, want %d", got, starts, want)
+	}
+
+	for i, want := range []uint64{1, 1, 0, 1} {
+		if requests[i] != want {
+			t.Errorf("got requests %v, want %d in window %d", requests, want, i)
+		}
+	}
+
+	if ws := w.Complete(); len(ws) != 0 {
+		t.Errorf("got %d windows reported twice", len(ws))
+	}
+}
+
+func TestWindowReporters(t *testing.T) {
+	t.Parallel()
+
+	newWindows := func() *Windows {
+		w := NewWindows(time.Minute)
+		for i := 0; i < 120; i++ {
+			w.Add(&Result{Timestamp: time.Unix(int64(i), 0).UTC(), Code: 200, Latency: time.Millisecond})
+		}
+		w.Close()
+		return w
+	}
+
+	var buf bytes.Buffer
+	rep := NewWindowTextReporter(newWindows())
+	for i := 0; i < 2; i++ {
+		if err := rep.Report(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "Start") ||
+		!strings.HasPrefix(lines[1], "1970-01-01T00:00:00Z") || !strings.HasPrefix(lines[2], "1970-01-01T00:01:00Z") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := NewWindowCSVReporter(newWindows()).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 3 || records[0][0] != "start" || records[1][2] != "60" || records[2][3] != "1" {
+		t.Errorf("unexpected CSV report: %v", records)
+	}
+
+	buf.Reset()
+	if err := NewWindowJSONReporter(newWindows()).Report(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var m WindowMetrics
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		} else if m.Requests != 60 || m.Latencies.P50 != time.Millisecond {
+			t.Errorf("window %d: got %+v", i, m)
+		}
+	}
+}
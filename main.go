@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -13,11 +14,15 @@ import (
 
 func main() {
 	commands := map[string]command{
-		"attack": attackCmd(),
-		"report": reportCmd(),
-		"plot":   plotCmd(),
-		"encode": encodeCmd(),
-		"dump":   dumpCmd(),
+		"attack":  attackCmd(),
+		"report":  reportCmd(),
+		"plot":    plotCmd(),
+		"encode":  encodeCmd(),
+		"dump":    dumpCmd(),
+		"replay":  replayCmd(),
+		"targets": targetsCmd(),
+		"check":   checkCmd(),
+		"compare": compareCmd(),
 	}
 
 	fs := flag.NewFlagSet("vegeta", flag.ExitOnError)
@@ -92,10 +97,24 @@ func main() {
 	if cmd, ok := commands[args[0]]; !ok {
 		log.Fatalf("Unknown command: %s", args[0])
 	} else if err := cmd.fn(args[1:]); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			log.Print(exit.err)
+			os.Exit(exit.code)
+		}
 		log.Fatal(err)
 	}
 }
 
+// exitError is an error with which vegeta exits with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
 // Set at linking time
 var (
 	Commit  string
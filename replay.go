@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const replayUsage = `Usage: vegeta replay [options] [<file>...]
+
+Replays the requests of an attack at the same offsets from its beginning as
+they were originally sent, optionally sped up or slowed down. Bodies and
+headers aren't recorded in results, so they are recovered from the targets
+given with -targets, matched by method and URL.
+
+Arguments:
+  <file>  A file with vegeta attack results encoded with one of
+          the supported encodings (gob | json | csv) [default: stdin]
+
+Options:
+  --speed    Speed factor of the replay relative to the original attack
+             (e.g. 2 replays twice as fast). [default: 1]
+
+  --targets  Targets file to recover request bodies and headers from, in
+             the given --format. [default: none]
+
+  The options of vegeta attack are accepted too, except for --rate,
+  --duration and --lazy, since the schedule of requests is given by
+  the results.
+
+Examples:
+  vegeta replay results.bin | vegeta report
+  vegeta replay -speed=2 -targets=targets.txt results.bin > replayed.bin
+`
+
+func replayCmd() command {
+	fs := flag.NewFlagSet("vegeta replay", flag.ExitOnError)
+	opts := newAttackOpts()
+	speed := fs.Float64("speed", 1, "Speed factor of the replay relative to the original attack (e.g. 2 replays twice as fast)")
+	fs.StringVar(&opts.targetsf, "targets", "", "Targets file to recover request bodies and headers from")
+	attackerFlags(fs, opts)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n", replayUsage)
+	}
+
+	return command{fs, func(args []string) error {
+		fs.Parse(args)
+		files := fs.Args()
+		if len(files) == 0 {
+			files = append(files, "stdin")
+		}
+		return replay(opts, *speed, files)
+	}}
+}
+
+// replay re-issues the requests recorded in the given results files
+// following their original schedule.
+func replay(opts *attackOpts, speed float64, files []string) error {
+	if speed <= 0 {
+		return errors.New("-speed must be bigger than zero")
+	}
+
+	dec, mc, err := decoder(files)
+	defer mc.Close()
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if opts.bodyf != "" {
+		if body, err = os.ReadFile(opts.bodyf); err != nil {
+			return fmt.Errorf("error reading %s: %s", opts.bodyf, err)
+		}
+	}
+
+	var recorded []vegeta.Target
+	if opts.targetsf != "" {
+		f, err := file(opts.targetsf, false)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %s", opts.targetsf, err)
+		}
+		defer f.Close()
+
+		tr, err := targeter(opts, f, body)
+		if err != nil {
+			return err
+		}
+
+		if recorded, err = vegeta.ReadAllTargets(tr); err != nil {
+			return err
+		}
+	}
+
+	targets, timestamps, err := replaySchedule(dec, recorded, body, opts.headers.Header)
+	if err != nil {
+		return err
+	}
+
+	atk, err := newAttacker(opts)
+	if err != nil {
+		return err
+	}
+
+	tr := vegeta.NewStaticTargeter(targets...)
+	return runAttack(atk, tr, vegeta.NewReplayPacer(timestamps, speed), 0, opts)
+}
+
+// replaySchedule decodes all results and returns the targets to replay in
+// the order they were originally hit along with their timestamps. Each
+// result's target is looked up by method and URL in the given recorded
+// targets, cycling through the ones that match, and is otherwise built out
+// of the given body and header.
+func replaySchedule(dec vegeta.Decoder, recorded []vegeta.Target, body []byte, hdr http.Header) ([]vegeta.Target, []time.Time, error) {
+	var results []vegeta.Result
+	for {
+		var r vegeta.Result
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		if r.Method == "" || r.URL == "" {
+			continue // Never sent, such as with dropped ticks.
+		}
+
+		results = append(results, vegeta.Result{
+			Timestamp: r.Timestamp,
+			Method:    r.Method,
+			URL:       r.URL,
+		})
+	}
+
+	if len(results) == 0 {
+		return nil, nil, errors.New("no requests to replay")
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+
+	matches := map[string][]vegeta.Target{}
+	for _, t := range recorded {
+		key := t.Method + " " + t.URL
+		matches[key] = append(matches[key], t)
+	}
+
+	var (
+		seen       = map[string]int{}
+		targets    = make([]vegeta.Target, len(results))
+		timestamps = make([]time.Time, len(results))
+	)
+
+	for i, r := range results {
+		timestamps[i] = r.Timestamp
+
+		key := r.Method + " " + r.URL
+		if tgts := matches[key]; len(tgts) > 0 {
+			targets[i] = tgts[seen[key]%len(tgts)]
+			seen[key]++
+			continue
+		}
+
+		targets[i] = vegeta.Target{
+			Method: r.Method,
+			URL:    r.URL,
+			Body:   body,
+			Header: hdr,
+		}
+	}
+
+	return targets, timestamps, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestReplaySchedule(t *testing.T) {
+	t.Parallel()
+
+	began := time.Unix(1000, 0)
+
+	var buf bytes.Buffer
+	enc := vegeta.NewEncoder(&buf)
+	for _, r := range []vegeta.Result{
+		{Seq: 1, Timestamp: began.Add(time.Second), Method: "POST", URL: "http://goku/things"},
+		{Seq: 0, Timestamp: began, Method: "POST", URL: "http://goku/things"},
+		{Seq: 2, Timestamp: began.Add(1500 * time.Millisecond), Error: vegeta.ErrTickDropped.Error()},
+		{Seq: 3, Timestamp: began.Add(2 * time.Second), Method: "GET", URL: "http://goku/unknown"},
+		{Seq: 4, Timestamp: began.Add(3 * time.Second), Method: "POST", URL: "http://goku/things"},
+	} {
+		r := r
+		if err := enc.Encode(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorded := []vegeta.Target{
+		{Method: "POST", URL: "http://goku/things", Body: []byte("1")},
+		{Method: "POST", URL: "http://goku/things", Body: []byte("2")},
+		{Method: "GET", URL: "http://goku/things"},
+	}
+
+	body := []byte("default")
+	hdr := http.Header{"X-Replay": []string{"1"}}
+
+	targets, timestamps, err := replaySchedule(vegeta.NewDecoder(&buf), recorded, body, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTargets := []vegeta.Target{
+		{Method: "POST", URL: "http://goku/things", Body: []byte("1")},
+		{Method: "POST", URL: "http://goku/things", Body: []byte("2")},
+		{Method: "GET", URL: "http://goku/unknown", Body: body, Header: hdr},
+		{Method: "POST", URL: "http://goku/things", Body: []byte("1")},
+	}
+
+	if diff := cmp.Diff(wantTargets, targets); diff != "" {
+		t.Errorf("unexpected targets (-want +got):\n%s", diff)
+	}
+
+	wantTimestamps := []time.Time{
+		began,
+		began.Add(time.Second),
+		began.Add(2 * time.Second),
+		began.Add(3 * time.Second),
+	}
+
+	if diff := cmp.Diff(wantTimestamps, timestamps); diff != "" {
+		t.Errorf("unexpected timestamps (-want +got):\n%s", diff)
+	}
+
+	if _, _, err := replaySchedule(vegeta.NewDecoder(&bytes.Buffer{}), nil, nil, nil); err == nil {
+		t.Error("want error with no requests to replay")
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/signal"
 	"strings"
 	"time"
 
 	vegeta "github.com/tsenart/vegeta/v12/lib"
+	"github.com/tsenart/vegeta/v12/lib/plot"
 )
 
 const reportUsage = `Usage: vegeta report [options] [<file>...]
@@ -21,8 +23,9 @@ Arguments:
           the supported encodings (gob | json | csv) [default: stdin]
 
 Options:
-  --type    Which report type to generate (text | json | hist[buckets] | hdrplot).
-            [default: text]
+  --type    Which report type to generate (text | json | csv | markdown |
+            html | hist[buckets] | hdrplot | hdrlog[interval]). The csv
+            type requires --window. [default: text]
 
   --every   Write the report to --output at every given interval (e.g 100ms)
             The default of 0 means the report will only be written after
@@ -30,19 +33,67 @@ Options:
 
   --output  Output file [default: stdout]
 
+  --by      Group results by one of url, method, status, attack or
+            header:<name> of the responses, and report on each group,
+            as a table row with the text type. [default: none]
+
+  --max-groups  Maximum number of groups, beyond which results are
+                reported in the last "(other)" group. [default: 100]
+
+  --url-rule  Normalize the URLs of results grouped --by=url matching a
+              regexp with a template, given as <regexp>=><template>
+              (e.g. /users/\d+=>/users/:id). Can be repeated multiple
+              times. [default: none]
+
+  --url-ids   Normalize path segments and query values of URLs grouped
+              --by=url which look like IDs, such as numbers, UUIDs and
+              long hex or alphanumeric tokens, to :id. [default: false]
+
+  --window  Report the metrics of each consecutive time window of the given
+            duration (e.g. 10s) of the attack instead of cumulative ones, as
+            a table with the text type, CSV with the csv type or NDJSON with
+            the json type. Windows are written as soon as they're complete,
+            so this works live during an attack too. [default: 0]
+
+  --estimator  Latency quantile estimator of the metrics, either a t-digest
+               or an HDR histogram with --hdr-digits significant digits
+               between --hdr-lowest and --hdr-highest, which the hdrlog
+               type always uses. (tdigest | hdr) [default: tdigest]
+
+  --hdr-digits   Significant digits of HDR histograms (1-5). [default: 3]
+  --hdr-lowest   Lowest latency tracked by HDR histograms. [default: 1µs]
+  --hdr-highest  Highest latency tracked by HDR histograms. [default: 1h]
+
 Examples:
   echo "GET http://:80" | vegeta attack -rate=10/s > results.gob
   echo "GET http://:80" | vegeta attack -rate=100/s | vegeta encode > results.json
   vegeta report < results.gob | rg -vU 'Error Set:.*' # Don't show errors
   vegeta report results.*
+  vegeta report -by=url results.gob
+  vegeta report -by=url -url-ids -url-rule='/users/[^/]+=>/users/:name' results.gob
+  vegeta report -estimator=hdr -hdr-digits=4 results.gob
+  vegeta report -type='hdrlog[10s]' results.gob > results.hlog
+  vegeta report -type=html -buckets='[0,10ms,50ms,100ms]' results.gob > report.html
+  vegeta attack -rate=100/s -duration=10m | vegeta report -window=1m -type=csv
 `
 
 func reportCmd() command {
 	fs := flag.NewFlagSet("vegeta report", flag.ExitOnError)
-	typ := fs.String("type", "text", "Report type to generate [text, json, hist[buckets], hdrplot]")
+	typ := fs.String("type", "text", "Report type to generate [text, json, csv (with -window), markdown, html, hist[buckets], hdrplot, hdrlog[interval]]")
 	every := fs.Duration("every", 0, "Report interval")
 	output := fs.String("output", "stdout", "Output file")
 	buckets := fs.String("buckets", "", "Histogram buckets, e.g.: \"[0,1ms,10ms]\"")
+	by := fs.String("by", "", "Group results by [url, method, status, attack, header:<name>]")
+	maxGroups := fs.Int("max-groups", vegeta.DefaultMaxGroups, "Maximum number of groups")
+	window := fs.Duration("window", 0, "Report the metrics of consecutive time windows of this duration [0 = disabled]")
+	estimator := fs.String("estimator", "tdigest", "Latency quantile estimator [tdigest, hdr]")
+	var urls vegeta.URLNormalizer
+	fs.Var(&urlRulesFlag{&urls.Rules}, "url-rule", "Normalize URLs grouped -by=url matching a regexp with a template, e.g. /users/\\d+=>/users/:id. Can be repeated multiple times")
+	fs.BoolVar(&urls.DetectIDs, "url-ids", false, "Normalize IDs in URLs grouped -by=url, such as numbers and UUIDs, to :id")
+	var hdr hdrOpts
+	fs.IntVar(&hdr.digits, "hdr-digits", vegeta.DefaultHDRDigits, "Significant digits of HDR histograms [1-5]")
+	fs.DurationVar(&hdr.lowest, "hdr-lowest", vegeta.DefaultHDRLowest, "Lowest latency tracked by HDR histograms")
+	fs.DurationVar(&hdr.highest, "hdr-highest", vegeta.DefaultHDRHighest, "Highest latency tracked by HDR histograms")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s\n", reportUsage)
@@ -54,12 +105,79 @@ func reportCmd() command {
 		if len(files) == 0 {
 			files = append(files, "stdin")
 		}
-		return report(files, *typ, *output, *every, *buckets)
+		switch *estimator {
+		case "tdigest":
+		case "hdr":
+			hdr.enabled = true
+		default:
+			return fmt.Errorf("bad -estimator value %q: not one of [tdigest, hdr]", *estimator)
+		}
+		if _, err := vegeta.NewHDRHistogram(hdr.lowest, hdr.highest, hdr.digits); err != nil {
+			return err
+		}
+		var normalizer *vegeta.URLNormalizer
+		if len(urls.Rules) > 0 || urls.DetectIDs {
+			if *by != "url" {
+				return fmt.Errorf("-url-rule and -url-ids require -by=url")
+			}
+			normalizer = &urls
+		}
+		if *window > 0 {
+			if *every > 0 || *by != "" || hdr.enabled {
+				return fmt.Errorf("-window can't be combined with -every, -by or -estimator=hdr")
+			}
+			return windowReport(files, *typ, *output, *window)
+		}
+		return report(files, *typ, *output, *every, *buckets, *by, normalizer, *maxGroups, hdr)
 	}}
 }
 
-func report(files []string, typ, output string, every time.Duration, bucketsStr string) error {
-	if len(typ) < 4 {
+// groupKey returns the GroupKey of the given -by value, with URLs
+// normalized by urls if not nil.
+func groupKey(by string, urls *vegeta.URLNormalizer) (vegeta.GroupKey, error) {
+	switch by {
+	case "url":
+		if urls != nil {
+			return vegeta.GroupByNormalizedURL(urls), nil
+		}
+		return vegeta.GroupByURL, nil
+	case "method":
+		return vegeta.GroupByMethod, nil
+	case "status":
+		return vegeta.GroupByStatus, nil
+	case "attack":
+		return vegeta.GroupByAttack, nil
+	}
+
+	if name, ok := strings.CutPrefix(by, "header:"); ok && name != "" {
+		return vegeta.GroupByHeader(name), nil
+	}
+
+	return nil, fmt.Errorf("bad -by value %q: not one of [url, method, status, attack, header:<name>]", by)
+}
+
+// hdrOpts are the parameters of the HDR histograms of a report.
+type hdrOpts struct {
+	enabled bool // Use HDR histograms instead of t-digests in Metrics.
+	digits  int
+	lowest  time.Duration
+	highest time.Duration
+}
+
+// newMetrics returns new Metrics, with an HDR histogram latency estimator
+// if enabled.
+func (o hdrOpts) newMetrics() *vegeta.Metrics {
+	var m vegeta.Metrics
+	if o.enabled {
+		m.Latencies.HDR, _ = vegeta.NewHDRHistogram(o.lowest, o.highest, o.digits)
+	}
+	return &m
+}
+
+func report(files []string, typ, output string, every time.Duration, bucketsStr, by string, urls *vegeta.URLNormalizer, maxGroups int, hdr hdrOpts) error {
+	if typ == "csv" {
+		return fmt.Errorf("the csv report type requires -window")
+	} else if len(typ) < 4 {
 		return fmt.Errorf("invalid report type: %s", typ)
 	}
 
@@ -78,26 +196,78 @@ func report(files []string, typ, output string, every time.Duration, bucketsStr
 	var (
 		rep    vegeta.Reporter
 		report vegeta.Report
+		key    vegeta.GroupKey
 	)
 
+	if by != "" {
+		if key, err = groupKey(by, urls); err != nil {
+			return err
+		}
+	}
+
 	switch typ {
 	case "plot":
 		return fmt.Errorf("The plot reporter has been deprecated and succeeded by the vegeta plot command")
 	case "text":
-		var m vegeta.Metrics
-		rep, report = vegeta.NewTextReporter(&m), &m
+		if key != nil {
+			g := vegeta.NewGroups(key, maxGroups, func() vegeta.Report { return hdr.newMetrics() })
+			rep, report = vegeta.NewGroupedTextReporter(g), g
+			break
+		}
+		m := hdr.newMetrics()
+		rep, report = vegeta.NewTextReporter(m), m
 	case "json":
-		var m vegeta.Metrics
+		var buckets vegeta.Buckets
+		if bucketsStr != "" {
+			if err := buckets.UnmarshalText([]byte(bucketsStr)); err != nil {
+				return err
+			}
+		}
+
+		newMetrics := func() vegeta.Report {
+			m := hdr.newMetrics()
+			if buckets != nil {
+				m.Histogram = &vegeta.Histogram{Buckets: buckets}
+			}
+			return m
+		}
+
+		if key != nil {
+			g := vegeta.NewGroups(key, maxGroups, newMetrics)
+			rep, report = vegeta.NewGroupedJSONReporter(g), g
+			break
+		}
+		m := newMetrics().(*vegeta.Metrics)
+		rep, report = vegeta.NewJSONReporter(m), m
+	case "markdown":
+		if key != nil {
+			g := vegeta.NewGroups(key, maxGroups, func() vegeta.Report { return hdr.newMetrics() })
+			rep = vegeta.NewGroupedReporter(g, func(r vegeta.Report) vegeta.Reporter {
+				return vegeta.NewMarkdownReporter(r.(*vegeta.Metrics))
+			})
+			report = g
+			break
+		}
+		m := hdr.newMetrics()
+		rep, report = vegeta.NewMarkdownReporter(m), m
+	case "html":
+		if key != nil {
+			return fmt.Errorf("the html report type can't be grouped by %s", by)
+		}
+		m := hdr.newMetrics()
 		if bucketsStr != "" {
 			m.Histogram = &vegeta.Histogram{}
 			if err := m.Histogram.Buckets.UnmarshalText([]byte(bucketsStr)); err != nil {
 				return err
 			}
 		}
-		rep, report = vegeta.NewJSONReporter(&m), &m
+		rep, report = plot.NewHTMLReporter(m, "Vegeta Report"), m
 	case "hdrplot":
-		var m vegeta.Metrics
-		rep, report = vegeta.NewHDRHistogramPlotReporter(&m), &m
+		if key != nil {
+			return fmt.Errorf("the hdrplot report type can't be grouped by %s", by)
+		}
+		m := hdr.newMetrics()
+		rep, report = vegeta.NewHDRHistogramPlotReporter(m), m
 	default:
 		switch {
 		case strings.HasPrefix(typ, "hist"):
@@ -111,7 +281,35 @@ func report(files []string, typ, output string, every time.Duration, bucketsStr
 			if err := hist.Buckets.UnmarshalText([]byte(bucketsStr)); err != nil {
 				return err
 			}
+			if key != nil {
+				g := vegeta.NewGroups(key, maxGroups, func() vegeta.Report {
+					return &vegeta.Histogram{Buckets: hist.Buckets}
+				})
+				rep = vegeta.NewGroupedReporter(g, func(r vegeta.Report) vegeta.Reporter {
+					return vegeta.NewHistogramReporter(r.(*vegeta.Histogram))
+				})
+				report = g
+				break
+			}
 			rep, report = vegeta.NewHistogramReporter(&hist), &hist
+		case strings.HasPrefix(typ, "hdrlog"):
+			if key != nil {
+				return fmt.Errorf("the hdrlog report type can't be grouped by %s", by)
+			}
+			interval := time.Second
+			if arg := typ[len("hdrlog"):]; arg != "" {
+				if len(arg) < 3 || arg[0] != '[' || arg[len(arg)-1] != ']' {
+					return fmt.Errorf("bad hdrlog interval: '%s'", arg)
+				}
+				if interval, err = time.ParseDuration(arg[1 : len(arg)-1]); err != nil {
+					return fmt.Errorf("bad hdrlog interval: %w", err)
+				}
+			}
+			l, err := vegeta.NewHDRLog(interval, hdr.lowest, hdr.highest, hdr.digits)
+			if err != nil {
+				return err
+			}
+			rep, report = vegeta.NewHDRLogReporter(l), l
 		default:
 			return fmt.Errorf("unknown report type: %q", typ)
 		}
@@ -155,6 +353,71 @@ decode:
 	return writeReport(rep, rc, out)
 }
 
+// windowReport writes the metrics of each complete time window of the given
+// size of the results in the given files as soon as it's complete.
+func windowReport(files []string, typ, output string, size time.Duration) error {
+	w := vegeta.NewWindows(size)
+
+	var rep vegeta.Reporter
+	switch typ {
+	case "text":
+		rep = vegeta.NewWindowTextReporter(w)
+	case "csv":
+		rep = vegeta.NewWindowCSVReporter(w)
+	case "json":
+		rep = vegeta.NewWindowJSONReporter(w)
+	default:
+		return fmt.Errorf("the %s report type can't be windowed", typ)
+	}
+
+	dec, mc, err := decoder(files)
+	defer mc.Close()
+	if err != nil {
+		return err
+	}
+
+	out, err := file(output, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt)
+
+decode:
+	for {
+		select {
+		case <-sigch:
+			break decode
+		default:
+			var r vegeta.Result
+			if err = dec.Decode(&r); err != nil {
+				if err == io.EOF {
+					break decode
+				}
+				return err
+			}
+
+			w.Add(&r)
+			if err = rep.Report(out); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Close()
+	if err = rep.Report(out); err != nil {
+		return err
+	}
+
+	if w.Late > 0 {
+		log.Printf("%d results arrived after their window was reported and were skipped", w.Late)
+	}
+
+	return nil
+}
+
 func writeReport(r vegeta.Reporter, rc vegeta.Closer, out io.Writer) error {
 	if rc != nil {
 		rc.Close()
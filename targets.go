@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const targetsUsage = `Usage: vegeta targets <command> [options] <file>
+
+Generates targets in the JSON format out of other sources.
+
+Commands:
+  from-openapi  Generates a target for every operation of an OpenAPI 3 spec,
+                in YAML or JSON. Path and query parameters, headers and
+                request bodies are filled from the examples of the spec or
+                from sample values derived from their schemas.
+
+Options:
+  --base-url      Base URL of targets. [default: the URL of the first
+                  server of the spec]
+
+  --include-tags  Only generate targets for operations with one of these
+                  tags (comma separated list). [default: none]
+
+  --exclude-tags  Don't generate targets for operations with any of these
+                  tags (comma separated list). [default: none]
+
+  --weight        Number of targets of an operation, given as
+                  operationId=N or 'METHOD /path=N', where 0 drops it.
+                  Can be repeated multiple times. [default: 1]
+
+  --output        Output file [default: stdout]
+
+Examples:
+  vegeta targets from-openapi spec.yaml | vegeta attack -format=json -duration=30s
+  vegeta targets from-openapi -base-url=http://localhost:8080 -include-tags=things \
+    -weight=listThings=10 -weight='DELETE /things/{id}=0' spec.yaml > targets.json
+`
+
+func targetsCmd() command {
+	fs := flag.NewFlagSet("vegeta targets", flag.ExitOnError)
+	output := fs.String("output", "stdout", "Output file")
+
+	var opts vegeta.OpenAPIOptions
+	fs.StringVar(&opts.BaseURL, "base-url", "", "Base URL of targets, defaulting to the URL of the first server of the spec")
+	fs.Var((*csl)(&opts.IncludeTags), "include-tags", "Only generate targets for operations with one of these tags (comma separated list)")
+	fs.Var((*csl)(&opts.ExcludeTags), "exclude-tags", "Don't generate targets for operations with any of these tags (comma separated list)")
+	fs.Var(&weightsFlag{&opts.Weights}, "weight", "Number of targets of an operation given as operationId=N or 'METHOD /path=N' (repeatable)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n", targetsUsage)
+	}
+
+	return command{fs, func(args []string) error {
+		if len(args) == 0 || args[0] != "from-openapi" {
+			fs.Usage()
+			os.Exit(1)
+		}
+
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("vegeta targets from-openapi takes one spec file, got %d", fs.NArg())
+		}
+
+		return targetsFromOpenAPI(fs.Arg(0), *output, opts)
+	}}
+}
+
+// targetsFromOpenAPI writes the targets of the given OpenAPI spec file to the
+// given output in the JSON format.
+func targetsFromOpenAPI(spec, output string, opts vegeta.OpenAPIOptions) error {
+	in, err := file(spec, false)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tgts, err := vegeta.ReadOpenAPITargets(in, opts)
+	if err != nil {
+		return err
+	}
+
+	out, err := file(output, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	enc := vegeta.NewJSONTargetEncoder(out)
+	for i := range tgts {
+		if err := enc(&tgts[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}